@@ -14,6 +14,7 @@ class APITester:
     def __init__(self, base_url="http://localhost:8080"):
         self.base_url = base_url
         self.token = None
+        self.refresh_token = None
         self.user_id = None
         self.test_results = []
 
@@ -155,6 +156,7 @@ class APITester:
 
             if success and "token" in response_data:
                 self.token = response_data["token"]
+                self.refresh_token = response_data.get("refresh_token")
 
             self.log_test(
                 "用户登录 - 正常情况",
@@ -198,12 +200,12 @@ class APITester:
 
     def test_token_refresh(self):
         """测试Token刷新"""
-        if not self.token:
-            print("[WARNING] 跳过Token刷新测试 - 没有有效的Token")
+        if not self.refresh_token:
+            print("[WARNING] 跳过Token刷新测试 - 没有有效的刷新Token")
             return
 
-        headers = {"Authorization": f"Bearer {self.token}"}
-        response = self.make_request("POST", "/auth/refresh", headers=headers, expected_status=200)
+        payload = {"refresh_token": self.refresh_token}
+        response = self.make_request("POST", "/auth/refresh", payload, expected_status=200)
 
         if response:
             success = response.status_code == 200
@@ -211,10 +213,11 @@ class APITester:
 
             if success and "token" in response_data:
                 self.token = response_data["token"]
+                self.refresh_token = response_data.get("refresh_token")
 
             self.log_test(
                 "Token刷新 - 正常情况",
-                "POST", "/auth/refresh", None, 200,
+                "POST", "/auth/refresh", payload, 200,
                 response.status_code, response_data, success
             )
 
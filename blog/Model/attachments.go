@@ -0,0 +1,17 @@
+package blog
+
+import "gorm.io/gorm"
+
+// 附件表模型，文件内容保存在存储中，这里只记录元数据
+type Attachment struct {
+	gorm.Model
+	PostID       uint   `gorm:"not null;index"`
+	UserID       int    `gorm:"not null"` // 上传者
+	FileName     string `gorm:"size:255;not null"`
+	ContentType  string `gorm:"size:100;not null"` // 根据文件内容检测，不信任客户端传入的类型
+	Size         int64  `gorm:"not null"`
+	StorageKey   string `gorm:"size:255;not null" json:"-"`
+	ThumbnailKey string `gorm:"size:255" json:"-"` // 只有图片才有缩略图
+	Width        int    // 图片宽高，非图片为0
+	Height       int
+}
@@ -5,7 +5,11 @@ import "gorm.io/gorm"
 // 评论表模型
 type Comment struct {
 	gorm.Model
-	Content string `gorm:"not null"`
-	PostID  int    `gorm:"not null"`
-	UserID  int    `gorm:"not null"`
+	Content  string `gorm:"not null"`
+	PostID   int    `gorm:"not null"`
+	UserID   int    `gorm:"not null"`
+	ParentID *uint  `gorm:"index"`                    // 回复的评论，顶层评论为空
+	RootID   uint   `gorm:"index;not null;default:0"` // 所在评论树的顶层评论，顶层评论为0
+	Depth    int    `gorm:"not null;default:0"`       // 顶层评论为0
+	Deleted  bool   `gorm:"not null;default:false"`   // 有回复的评论被删除后保留为墓碑，内容清空
 }
@@ -0,0 +1,28 @@
+package blog
+
+import "time"
+
+// 文章点赞表模型，同一用户对同一文章只有一条记录
+type PostLike struct {
+	ID        uint `gorm:"primarykey"`
+	PostID    uint `gorm:"not null;uniqueIndex:idx_post_like_user"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_post_like_user;index"`
+	CreatedAt time.Time
+}
+
+// 文章收藏表模型
+type Bookmark struct {
+	ID        uint `gorm:"primarykey"`
+	PostID    uint `gorm:"not null;uniqueIndex:idx_bookmark_user"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_bookmark_user;index"`
+	CreatedAt time.Time
+}
+
+// 评论表情回应表模型，同一用户可以对同一评论做出多种回应
+type CommentReaction struct {
+	ID        uint   `gorm:"primarykey"`
+	CommentID uint   `gorm:"not null;uniqueIndex:idx_comment_reaction"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_comment_reaction"`
+	Reaction  string `gorm:"size:16;not null;uniqueIndex:idx_comment_reaction"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,48 @@
+package blog
+
+import "gorm.io/gorm"
+
+// SearchSyncer 模型变更时同步搜索索引，由上层注入
+// tx为触发钩子的数据库会话，需要查询关联数据时使用，避免在事务中另开连接
+// 实现可以根据tx的上下文把索引变更推迟到事务提交之后
+type SearchSyncer interface {
+	PostSaved(tx *gorm.DB, p *Post) error
+	PostDeleted(tx *gorm.DB, id uint) error
+	CommentSaved(tx *gorm.DB, c *Comment) error
+	CommentDeleted(tx *gorm.DB, id uint) error
+}
+
+// 搜索索引钩子，为空时不同步
+var SearchHook SearchSyncer
+
+// AfterSave 文章创建或更新后同步索引
+func (p *Post) AfterSave(tx *gorm.DB) error {
+	if SearchHook == nil {
+		return nil
+	}
+	return SearchHook.PostSaved(tx, p)
+}
+
+// AfterDelete 文章删除后从索引中移除
+func (p *Post) AfterDelete(tx *gorm.DB) error {
+	if SearchHook == nil || p.ID == 0 {
+		return nil
+	}
+	return SearchHook.PostDeleted(tx, p.ID)
+}
+
+// AfterSave 评论创建或更新后同步索引
+func (c *Comment) AfterSave(tx *gorm.DB) error {
+	if SearchHook == nil {
+		return nil
+	}
+	return SearchHook.CommentSaved(tx, c)
+}
+
+// AfterDelete 评论删除后从索引中移除
+func (c *Comment) AfterDelete(tx *gorm.DB) error {
+	if SearchHook == nil || c.ID == 0 {
+		return nil
+	}
+	return SearchHook.CommentDeleted(tx, c.ID)
+}
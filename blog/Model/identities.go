@@ -0,0 +1,15 @@
+package blog
+
+import "time"
+
+// 外部身份表模型，把OIDC身份提供方的账号关联到博客用户
+// 同一个提供方的同一个subject只能关联一个用户，一个用户可以关联多个提供方
+type UserIdentity struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"-"`
+	Provider    string    `gorm:"size:64;not null;uniqueIndex:idx_identity_subject" json:"provider"` // 配置中的提供方名称
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_identity_subject" json:"-"`       // ID Token中的sub
+	Email       string    `gorm:"size:255" json:"email"`                                             // 最近一次登录时提供方返回的邮箱
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
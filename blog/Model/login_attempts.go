@@ -0,0 +1,15 @@
+package blog
+
+import "time"
+
+// 登录记录表模型，成功和失败的登录都会记录，用于失败计数和登录历史
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    *uint     `gorm:"index"`                   // 邮箱不存在时为空
+	Email     string    `gorm:"size:255;not null;index"` // 统一转为小写
+	IP        string    `gorm:"size:64;not null;index"`
+	UserAgent string    `gorm:"size:255"`
+	Success   bool      `gorm:"not null"`
+	Reason    string    `gorm:"size:32"` // 失败原因：invalid_credentials、invalid_2fa、locked、email_unverified
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -1,11 +1,35 @@
 package blog
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 文章状态
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
 
 // 文章表模型
 type Post struct {
 	gorm.Model
-	Title   string `gorm:"not null"`
-	Content string `gorm:"not null"`
-	UserID  int    `gorm:"not null"`
+	Title       string     `gorm:"not null"`
+	Content     string     `gorm:"not null"`           // Markdown原文
+	ContentHTML string     `gorm:"type:text" json:"-"` // 渲染并过滤后的HTML，通过format=html返回
+	UserID      int        `gorm:"not null"`
+	Status      string     `gorm:"size:16;not null;default:published;index"` // 已有文章默认视为已发布
+	PublishAt   *time.Time `gorm:"index"`                                    // 定时发布时间，仅scheduled状态使用
+	PublishedAt *time.Time // 最近一次发布的时间
+
+	// 冗余计数，只通过原子的自增自减更新，保存文章时不会覆盖
+	LikesCount     int `gorm:"not null;default:0"`
+	BookmarksCount int `gorm:"not null;default:0"`
+	CommentsCount  int `gorm:"not null;default:0"` // 不含已删除的墓碑评论
+
+	Tags       []Tag      `gorm:"many2many:post_tags"`
+	Categories []Category `gorm:"many2many:post_categories"`
 }
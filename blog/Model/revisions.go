@@ -0,0 +1,14 @@
+package blog
+
+import "gorm.io/gorm"
+
+// 文章版本表模型，每次修改标题或内容都会保存一份完整的快照
+type PostRevision struct {
+	gorm.Model
+	PostID       uint   `gorm:"not null;uniqueIndex:idx_post_revision"`
+	Number       int    `gorm:"not null;uniqueIndex:idx_post_revision"` // 文章内从1开始递增的版本号
+	UserID       int    `gorm:"not null"`                               // 修改人
+	Title        string `gorm:"not null"`
+	Content      string `gorm:"not null"`
+	RestoredFrom *int   // 由回滚产生时记录被恢复的版本号
+}
@@ -0,0 +1,17 @@
+package blog
+
+import "gorm.io/gorm"
+
+// 标签表模型，标签在给文章打标签时自动创建
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"size:32;uniqueIndex;not null"` // 统一为小写
+}
+
+// 分类表模型，分类需要预先创建
+type Category struct {
+	gorm.Model
+	Name        string `gorm:"size:64;not null"`
+	Slug        string `gorm:"size:64;uniqueIndex;not null"`
+	Description string
+}
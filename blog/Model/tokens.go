@@ -0,0 +1,49 @@
+package blog
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 吊销记录类型
+const (
+	RevokedKindAccess = "access" // 单个访问Token
+	RevokedKindFamily = "family" // 整个Token家族，JTI字段存放家族ID
+	RevokedKindAction = "action" // 已使用的邮箱验证或密码重置Token
+)
+
+// 刷新Token表模型
+// 同一次登录产生的刷新Token属于同一个家族，每次刷新都会轮换出新的Token
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"` // 只保存Token的SHA-256摘要
+	FamilyID  string     `gorm:"size:36;index;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // 已被轮换的时间，再次使用视为重放
+	RevokedAt *time.Time
+	MFA       bool `gorm:"not null;default:false"` // 登录时是否完成了两步验证，轮换后保持不变
+}
+
+// 访问Token吊销表模型
+type RevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"column:jti;size:36;uniqueIndex;not null"`
+	Kind      string    `gorm:"size:16;not null"`
+	ExpiresAt time.Time `gorm:"not null;index"` // 超过该时间后对应Token已自然失效，可以清理
+}
+
+// 个人访问Token表模型，供脚本和机器人调用API，只保存Token的摘要
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"-"`
+	Name        string     `gorm:"size:64;not null" json:"name"`
+	TokenHash   string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // Token的SHA-256摘要
+	TokenPrefix string     `gorm:"size:16;not null" json:"token_prefix"`  // Token开头几位，便于用户辨认
+	Scopes      string     `gorm:"size:255;not null" json:"-"`            // 逗号分隔的授权范围
+	ExpiresAt   *time.Time `json:"expires_at"`                            // 为空表示永不过期
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -1,11 +1,31 @@
 package blog
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // 用户表模型
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique;not null"`
-	Password string `gorm:"not null"`
-	Email    string `gorm:"unique;not null"`
+	Username        string     `gorm:"unique;not null"`
+	Password        string     `gorm:"not null"`
+	Email           string     `gorm:"unique;not null"`
+	Role            string     `gorm:"size:16;not null;default:author"` // admin、editor、author、reader
+	Permissions     string     `gorm:"size:255"`                        // 角色之外额外授予的权限，逗号分隔
+	EmailVerifiedAt *time.Time // 邮箱验证时间，为空表示尚未验证
+	TOTPSecret      string     `gorm:"size:64" json:"-"` // Base32编码的TOTP密钥，登记后确认之前也会保存
+	TOTPEnabledAt   *time.Time // 两步验证启用时间，为空表示未启用
+	TOTPLastStep    int64      `json:"-"`       // 最后一次使用的时间步，防止同一个验证码被重放
+	Locale          string     `gorm:"size:16"` // 语言偏好，如zh-CN、en-US，为空表示跟随Accept-Language
+}
+
+// 两步验证恢复码表模型，每个恢复码只能使用一次
+type RecoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null"` // 只保存恢复码的SHA-256摘要
+	UsedAt    *time.Time
+	CreatedAt time.Time
 }
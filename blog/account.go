@@ -0,0 +1,190 @@
+package blog
+
+import (
+	"context"
+	model "job/blog/Model"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ForgotPasswordRateLimit 找回密码和重发验证邮件的限流额度，按IP计算，两个接口分别计数
+var ForgotPasswordRateLimit = RateLimit{Requests: 5, Per: time.Hour}
+
+// 邮箱请求结构（找回密码、重发验证邮件）
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// 邮箱验证请求结构
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// 重置密码请求结构
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// actionLink 邮件中的链接
+func actionLink(path, token string) string {
+	return AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// mailLocale 邮件使用收件人设置的语言，没有设置时使用当前请求的语言
+func mailLocale(c *gin.Context, user *model.User) string {
+	if IsSupportedLocale(user.Locale) {
+		return user.Locale
+	}
+	return RequestLocale(c)
+}
+
+// sendVerificationEmail 在后台发送邮箱验证邮件
+func sendVerificationEmail(c *gin.Context, user model.User) {
+	locale := mailLocale(c, &user)
+	sendInBackground(c.Request.Context(), "Failed to send verification email", user.ID, func(ctx context.Context) error {
+		token, err := issueActionToken(&user, PurposeVerifyEmail, VerifyEmailTokenTTL)
+		if err != nil {
+			return err
+		}
+		return mailer.Send(ctx, Mail{
+			To:      user.Email,
+			Subject: translate(locale, "mail.verify_email.subject"),
+			Body:    translate(locale, "mail.verify_email.body", user.Username, VerifyEmailTokenTTL, actionLink("/auth/verify", token)),
+		})
+	})
+}
+
+// sendPasswordResetEmail 在后台发送密码重置邮件，链接指向前端的重置页面，由页面调用 POST /auth/reset
+func sendPasswordResetEmail(c *gin.Context, user model.User) {
+	locale := mailLocale(c, &user)
+	sendInBackground(c.Request.Context(), "Failed to send password reset email", user.ID, func(ctx context.Context) error {
+		token, err := issueActionToken(&user, PurposeResetPassword, ResetPasswordTokenTTL)
+		if err != nil {
+			return err
+		}
+		return mailer.Send(ctx, Mail{
+			To:      user.Email,
+			Subject: translate(locale, "mail.reset_password.subject"),
+			Body:    translate(locale, "mail.reset_password.body", user.Username, ResetPasswordTokenTTL, actionLink("/reset-password", token)),
+		})
+	})
+}
+
+// abortActionToken 一次性Token无效或已使用时返回400
+func abortActionToken(c *gin.Context, err error) {
+	respondError(c, classifyError(err, "Failed to use token"))
+}
+
+// verifyEmail GET|POST /auth/verify
+// 邮件中的链接直接以GET访问，token也可以放在JSON请求体中
+func verifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	user, claims, err := parseActionToken(req.Token, PurposeVerifyEmail)
+	if err != nil {
+		abortActionToken(c, err)
+		return
+	}
+	err = GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := consumeActionToken(tx, claims); err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := tx.Model(user).Update("email_verified_at", now).Error; err != nil {
+			return err
+		}
+		return promoteVerifiedAdmin(tx, user)
+	})
+	if err != nil {
+		abortActionToken(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": T(c, "email_verified")})
+}
+
+// resendVerification POST /auth/verify/resend
+// 无论邮箱是否存在、是否已验证都立即返回202，邮件在后台发送
+func resendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	var user model.User
+	if err := GetDB().Where("email = ?", req.Email).First(&user).Error; err == nil && user.EmailVerifiedAt == nil {
+		sendVerificationEmail(c, user)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": T(c, "mail_sent")})
+}
+
+// forgotPassword POST /auth/forgot
+// 无论邮箱是否存在都立即返回202，邮件在后台发送，响应时间不会暴露邮箱是否注册
+func forgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	var user model.User
+	if err := GetDB().Where("email = ?", req.Email).First(&user).Error; err == nil {
+		sendPasswordResetEmail(c, user)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": T(c, "mail_sent")})
+}
+
+// resetPassword POST /auth/reset
+// 重置成功后吊销该用户的全部会话；能收到邮件也说明邮箱属于该用户，顺便标记为已验证
+func resetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	user, claims, err := parseActionToken(req.Token, PurposeResetPassword)
+	if err != nil {
+		abortActionToken(c, err)
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		respondError(c, InternalError("Failed to hash password", err))
+		return
+	}
+	err = GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := consumeActionToken(tx, claims); err != nil {
+			return err
+		}
+		updates := map[string]any{"password": string(hashed)}
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			updates["email_verified_at"] = now
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 能收到重置邮件说明拥有该邮箱
+		return promoteVerifiedAdmin(tx, user)
+	})
+	if err != nil {
+		abortActionToken(c, err)
+		return
+	}
+	if err := RevokeUserSessions(user.ID); err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to revoke sessions after password reset", "user_id", user.ID, "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": T(c, "password_reset")})
+}
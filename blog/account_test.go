@@ -0,0 +1,206 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	model "job/blog/Model"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var tokenInMail = regexp.MustCompile(`token=(\S+)`)
+
+// lastMailToken 取出最后一封邮件链接中的Token
+func lastMailToken(t *testing.T, m *MemoryMailer) string {
+	t.Helper()
+	sent := m.Sent()
+	if len(sent) == 0 {
+		t.Fatal("no mail sent")
+	}
+	match := tokenInMail.FindStringSubmatch(sent[len(sent)-1].Body)
+	if match == nil {
+		t.Fatalf("no token in mail: %s", sent[len(sent)-1].Body)
+	}
+	token, _ := url.QueryUnescape(match[1])
+	return token
+}
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	r := newTestRouter(t)
+	m := mailer.(*MemoryMailer)
+	login := func(password string) *LoginResponse {
+		w := doRequest(r, http.MethodPost, "/auth/login", "", gin.H{"email": "bob@example.com", "password": password})
+		if w.Code != http.StatusOK {
+			return nil
+		}
+		var res LoginResponse
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return &res
+	}
+
+	w := doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "bob", "email": "bob@example.com", "password": "secret1"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body)
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/login", "", gin.H{"email": "bob@example.com", "password": "secret1"}); w.Code != http.StatusForbidden {
+		t.Fatalf("unverified login: %d", w.Code)
+	}
+
+	verify := lastMailToken(t, m)
+	// 一次性Token不能当作访问Token
+	if w := doRequest(r, http.MethodGet, "/api/profile", verify, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("verify token accepted as access token: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/auth/verify?token="+url.QueryEscape(verify), "", nil); w.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", w.Code, w.Body)
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/verify", "", gin.H{"token": verify}); w.Code != http.StatusBadRequest {
+		t.Fatalf("reused verify token: %d", w.Code)
+	}
+	session := login("secret1")
+	if session == nil {
+		t.Fatal("login after verification failed")
+	}
+
+	// 未注册的邮箱返回同样的响应，但不发送邮件
+	sent := len(m.Sent())
+	if w := doRequest(r, http.MethodPost, "/auth/forgot", "", gin.H{"email": "nobody@example.com"}); w.Code != http.StatusAccepted || len(m.Sent()) != sent {
+		t.Fatalf("forgot unknown email: %d, mails %d", w.Code, len(m.Sent()))
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/forgot", "", gin.H{"email": "bob@example.com"}); w.Code != http.StatusAccepted {
+		t.Fatalf("forgot: %d", w.Code)
+	}
+	reset := lastMailToken(t, m)
+	if w := doRequest(r, http.MethodPost, "/auth/verify", "", gin.H{"token": reset}); w.Code != http.StatusBadRequest {
+		t.Fatalf("reset token accepted for verification: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/reset", "", gin.H{"token": reset, "password": "newsecret"}); w.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", w.Code, w.Body)
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/reset", "", gin.H{"token": reset, "password": "another"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("reused reset token: %d", w.Code)
+	}
+
+	if login("secret1") != nil || login("newsecret") == nil {
+		t.Fatal("password was not changed")
+	}
+	// 重置密码后旧会话失效
+	if w := doRequest(r, http.MethodPost, "/auth/refresh", "", gin.H{"refresh_token": session.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("old session still valid: %d", w.Code)
+	}
+}
+
+func TestRegisterDuplicateUsername(t *testing.T) {
+	r := newTestRouter(t)
+	m := mailer.(*MemoryMailer)
+	if w := doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "bob", "email": "bob@example.com", "password": "secret1"}); w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body)
+	}
+	sent := len(m.Sent())
+
+	w := doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "bob", "email": "other@example.com", "password": "secret1"})
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	if w.Code != http.StatusConflict || p.Code != "user_exists" {
+		t.Fatalf("duplicate username: %d %s", w.Code, w.Body)
+	}
+	if len(m.Sent()) != sent {
+		t.Fatal("verification email sent for a user that was not created")
+	}
+}
+
+func TestAdminEmailPromotedAfterVerification(t *testing.T) {
+	r := newTestRouter(t)
+	m := mailer.(*MemoryMailer)
+	prevAdmins, prevRequire := AdminEmails, RequireEmailVerification
+	AdminEmails, RequireEmailVerification = []string{"Boss@example.com"}, false
+	t.Cleanup(func() { AdminEmails, RequireEmailVerification = prevAdmins, prevRequire })
+
+	// 未验证邮箱之前只有默认角色，即使不要求验证邮箱就能登录
+	w := doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "boss", "email": "boss@example.com", "password": "secret1"})
+	var res struct{ User model.User }
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusCreated || res.User.Role != DefaultRole {
+		t.Fatalf("register: %d %s", w.Code, w.Body)
+	}
+
+	if w := doRequest(r, http.MethodPost, "/auth/verify", "", gin.H{"token": lastMailToken(t, m)}); w.Code != http.StatusOK {
+		t.Fatalf("verify: %d %s", w.Code, w.Body)
+	}
+	var user model.User
+	GetDB().First(&user, res.User.ID)
+	if user.Role != RoleAdmin {
+		t.Fatalf("role after verification = %q, want admin", user.Role)
+	}
+}
+
+// blockingMailer 在release关闭之前阻塞发送，用来确认接口不等待邮件发送
+type blockingMailer struct {
+	release chan struct{}
+	MemoryMailer
+}
+
+func (m *blockingMailer) Send(ctx context.Context, mail Mail) error {
+	<-m.release
+	return m.MemoryMailer.Send(ctx, mail)
+}
+
+func TestForgotPasswordDoesNotWaitForMail(t *testing.T) {
+	r := newTestRouter(t)
+	createTestUser(t, "bob")
+	m := &blockingMailer{release: make(chan struct{})}
+	mailer = m
+
+	done := make(chan int)
+	go func() {
+		done <- doRequest(r, http.MethodPost, "/auth/forgot", "", gin.H{"email": "bob@example.com"}).Code
+	}()
+	select {
+	case code := <-done:
+		if code != http.StatusAccepted {
+			t.Fatalf("forgot: %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("forgot password waited for the mail to be sent")
+	}
+	close(m.release)
+	if sent := m.Sent(); len(sent) != 1 || sent[0].Subject != "Reset your password" {
+		t.Fatalf("sent = %+v", sent)
+	}
+}
+
+func TestMailUsesRecipientLocale(t *testing.T) {
+	r := newTestRouter(t)
+	m := mailer.(*MemoryMailer)
+	user := createTestUser(t, "bob")
+	GetDB().Model(user).Update("locale", LocaleZhCN)
+
+	doRequest(r, http.MethodPost, "/auth/forgot", "", gin.H{"email": "bob@example.com"})
+	sent := m.Sent()
+	if len(sent) != 1 || sent[0].Subject != "重置你的密码" {
+		t.Fatalf("sent = %+v", sent)
+	}
+}
+
+func TestResendVerificationHasOwnRateLimit(t *testing.T) {
+	prev := ForgotPasswordRateLimit
+	ForgotPasswordRateLimit = RateLimit{Requests: 1, Per: time.Hour}
+	t.Cleanup(func() { ForgotPasswordRateLimit = prev })
+	r := newTestRouter(t)
+
+	body := gin.H{"email": "nobody@example.com"}
+	if w := doRequest(r, http.MethodPost, "/auth/forgot", "", body); w.Code != http.StatusAccepted {
+		t.Fatalf("forgot: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/forgot", "", body); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second forgot: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/auth/verify/resend", "", body); w.Code != http.StatusAccepted {
+		t.Fatalf("resend shares the forgot bucket: %d", w.Code)
+	}
+}
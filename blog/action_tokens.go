@@ -0,0 +1,124 @@
+package blog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	model "job/blog/Model"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 一次性Token的用途，写在sub中，不同用途的Token不能混用
+const (
+	PurposeVerifyEmail   = "verify-email"
+	PurposeResetPassword = "reset-password"
+)
+
+// 一次性Token的有效期
+var (
+	VerifyEmailTokenTTL   = 24 * time.Hour
+	ResetPasswordTokenTTL = time.Hour
+)
+
+var (
+	ErrInvalidActionToken = ValidationError("invalid_action_token", "Invalid or expired token")
+	ErrActionTokenUsed    = ValidationError("action_token_used", "Token has already been used")
+)
+
+// actionClaims 邮箱验证和密码重置Token的Claims，与访问Token使用同一套签名密钥
+type actionClaims struct {
+	UserID uint `json:"uid"`
+	// 签发时邮箱（验证邮箱）或密码摘要（其他用途）的指纹，邮箱或密码变化后旧Token自动失效
+	Fingerprint string `json:"fp"`
+	jwt.RegisteredClaims
+}
+
+// actionFingerprint 按用途取用户的指纹
+func actionFingerprint(user *model.User, purpose string) string {
+	source := user.Password
+	if purpose == PurposeVerifyEmail {
+		source = user.Email
+	}
+	sum := sha256.Sum256([]byte(purpose + ":" + source))
+	return hex.EncodeToString(sum[:8])
+}
+
+// issueActionToken 签发一次性Token
+func issueActionToken(user *model.User, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := actionClaims{
+		UserID:      user.ID,
+		Fingerprint: actionFingerprint(user, purpose),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "jss",
+			Subject:   purpose,
+		},
+	}
+	return signClaims(claims)
+}
+
+// signClaims 使用当前签名密钥签发Token，通过kid标明签名密钥
+func signClaims(claims jwt.Claims) (string, error) {
+	key := currentKeySet().signingKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Private)
+}
+
+// keySetKeyFunc 按kid查找验证密钥，并拒绝与密钥不符的签名算法
+func keySetKeyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := currentKeySet().verificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Public, nil
+}
+
+// parseActionToken 校验签名、用途、有效期和指纹，返回Token对应的用户
+func parseActionToken(raw, purpose string) (*model.User, *actionClaims, error) {
+	claims := &actionClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, keySetKeyFunc, jwt.WithSubject(purpose), jwt.WithExpirationRequired())
+	if err != nil || claims.ID == "" {
+		return nil, nil, ErrInvalidActionToken
+	}
+
+	var user model.User
+	if err := GetDB().First(&user, claims.UserID).Error; err != nil {
+		return nil, nil, ErrInvalidActionToken
+	}
+	if claims.Fingerprint != actionFingerprint(&user, purpose) {
+		return nil, nil, ErrInvalidActionToken
+	}
+	return &user, claims, nil
+}
+
+// consumeActionToken 把Token记为已使用，已经用过时返回ErrActionTokenUsed
+// 借用吊销表的唯一索引，并发使用同一个Token时只有一个请求成功
+func consumeActionToken(tx *gorm.DB, claims *actionClaims) error {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RevokedToken{
+		JTI:       claims.ID,
+		Kind:      model.RevokedKindAction,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrActionTokenUsed
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package blog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	model "job/blog/Model"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AllowedUploadTypes 允许上传的文件类型，按文件内容检测
+var AllowedUploadTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp",
+	"application/pdf", "text/plain", "application/zip",
+}
+
+const (
+	ThumbnailSize  = 320        // 缩略图最长边的像素数
+	maxImagePixels = 40_000_000 // 超过这个像素数的图片不生成缩略图，防止解压炸弹
+)
+
+// 附件相关错误
+var (
+	ErrUploadTooLarge        = NewError(KindTooLarge, "file_too_large", "File is too large")
+	ErrUploadMissing         = ValidationError("file_required", "file is required")
+	ErrUploadUnreadable      = ValidationError("file_unreadable", "Failed to read file")
+	ErrUnsupportedUpload     = NewError(KindUnsupported, "unsupported_file_type", "Unsupported file type")
+	ErrAttachmentNotFound    = NotFoundError("attachment_not_found", "Attachment not found")
+	ErrAttachmentFileMissing = NotFoundError("file_not_found", "File not found")
+	ErrNoThumbnail           = NotFoundError("thumbnail_not_found", "Attachment has no thumbnail")
+	ErrNotAttachmentOwner    = ForbiddenError("not_attachment_owner", "You are not the owner of this attachment")
+)
+
+// detectUploadType 根据文件内容检测类型，不在白名单中时返回空字符串
+func detectUploadType(data []byte) (contentType, ext string) {
+	mt := mimetype.Detect(data)
+	for _, allowed := range AllowedUploadTypes {
+		if mt.Is(allowed) {
+			return mt.String(), mt.Extension()
+		}
+	}
+	return "", ""
+}
+
+// makeThumbnail 为图片生成缩略图，返回缩略图数据、类型和原图宽高
+// 无法解码的图片（如webp）只返回错误，不影响上传
+func makeThumbnail(data []byte) ([]byte, string, int, int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, "", cfg.Width, cfg.Height, errors.New("image too large for thumbnail")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", cfg.Width, cfg.Height, err
+	}
+
+	var buf bytes.Buffer
+	thumb := resizeImage(img, ThumbnailSize)
+	contentType := "image/png"
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, thumb)
+	}
+	return buf.Bytes(), contentType, cfg.Width, cfg.Height, err
+}
+
+// resizeImage 按区域平均等比缩小图片，使最长边不超过maxSide
+func resizeImage(src image.Image, maxSide int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+	scale := float64(maxSide) / float64(max(w, h))
+	nw, nh := max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+	dst := image.NewRGBA64(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0, y1 := b.Min.Y+y*h/nh, b.Min.Y+(y+1)*h/nh
+		for x := 0; x < nw; x++ {
+			x0, x1 := b.Min.X+x*w/nw, b.Min.X+(x+1)*w/nw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// uploadAttachment POST /api/posts/:id/attachments
+// multipart表单，文件字段名为file
+func uploadAttachment(c *gin.Context) {
+	post, ok := findEditablePost(c)
+	if !ok {
+		return
+	}
+	// 为multipart的边界和其他字段预留1MB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize+1<<20)
+	fh, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || err == nil && fh.Size > MaxUploadSize {
+		respondError(c, ErrUploadTooLarge.WithDetail("file_too_large.limit", MaxUploadSize))
+		return
+	}
+	if err != nil {
+		respondError(c, ErrUploadMissing)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respondError(c, ErrUploadUnreadable.WithCause(err))
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		respondError(c, ErrUploadUnreadable.WithCause(err))
+		return
+	}
+
+	contentType, ext := detectUploadType(data)
+	if contentType == "" {
+		respondError(c, ErrUnsupportedUpload)
+		return
+	}
+
+	userID, _ := GetCurrentUserID(c)
+	name := []rune(filepath.Base(fh.Filename))
+	dir, objectID := time.Now().Format("2006/01"), uuid.NewString()
+	att := model.Attachment{
+		PostID:      post.ID,
+		UserID:      userID,
+		FileName:    string(name[:min(len(name), 255)]),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  fmt.Sprintf("attachments/%s/%s%s", dir, objectID, ext),
+	}
+
+	ctx := c.Request.Context()
+	if err := storage.Put(ctx, att.StorageKey, data, contentType); err != nil {
+		respondError(c, InternalError("Failed to store file", err))
+		return
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, thumbType, width, height, err := makeThumbnail(data)
+		att.Width, att.Height = width, height
+		if err == nil {
+			key := fmt.Sprintf("thumbnails/%s/%s.png", dir, objectID)
+			if thumbType == "image/jpeg" {
+				key = strings.TrimSuffix(key, ".png") + ".jpg"
+			}
+			if err := storage.Put(ctx, key, thumb, thumbType); err == nil {
+				att.ThumbnailKey = key
+			}
+		}
+	}
+	if err := GetDB().Create(&att).Error; err != nil {
+		deleteAttachmentObjects(c, &att)
+		respondError(c, InternalError("Failed to save attachment", err))
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/attachments/%d", att.ID))
+	respondData(c, http.StatusCreated, att)
+}
+
+// listAttachments GET /api/posts/:id/attachments
+func listAttachments(c *gin.Context) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	var atts []model.Attachment
+	if err := GetDB().Where("post_id = ?", post.ID).Order("id").Find(&atts).Error; err != nil {
+		respondError(c, InternalError("Failed to load attachments", err))
+		return
+	}
+	respondData(c, http.StatusOK, atts)
+}
+
+// findAttachment 按路径ID查找附件，所属文章需要对当前用户可见
+func findAttachment(c *gin.Context) (*model.Attachment, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+	var att model.Attachment
+	if err := GetDB().First(&att, id).Error; err != nil {
+		respondError(c, ErrAttachmentNotFound)
+		return nil, false
+	}
+	if _, err := visiblePost(c, int(att.PostID)); err != nil {
+		respondError(c, ErrAttachmentNotFound)
+		return nil, false
+	}
+	return &att, true
+}
+
+// serveObject 从存储读取对象并返回，禁止浏览器再次猜测类型
+func serveObject(c *gin.Context, key, contentType string, size int64, disposition string) {
+	r, err := storage.Open(c.Request.Context(), key)
+	if errors.Is(err, ErrObjectNotFound) {
+		respondError(c, ErrAttachmentFileMissing)
+		return
+	}
+	if err != nil {
+		respondError(c, InternalError("Failed to read file", err))
+		return
+	}
+	defer r.Close()
+	c.DataFromReader(http.StatusOK, size, contentType, r, map[string]string{
+		"Content-Disposition":    disposition,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// downloadAttachment GET /api/attachments/:id
+// 图片直接显示，其他文件作为下载返回
+func downloadAttachment(c *gin.Context) {
+	att, ok := findAttachment(c)
+	if !ok {
+		return
+	}
+	disposition := "attachment"
+	if strings.HasPrefix(att.ContentType, "image/") {
+		disposition = "inline"
+	}
+	disposition = fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, strings.ReplaceAll(url.PathEscape(att.FileName), "'", "%27"))
+	serveObject(c, att.StorageKey, att.ContentType, att.Size, disposition)
+}
+
+// attachmentThumbnail GET /api/attachments/:id/thumbnail
+func attachmentThumbnail(c *gin.Context) {
+	att, ok := findAttachment(c)
+	if !ok {
+		return
+	}
+	if att.ThumbnailKey == "" {
+		respondError(c, ErrNoThumbnail)
+		return
+	}
+	contentType := "image/png"
+	if strings.HasSuffix(att.ThumbnailKey, ".jpg") {
+		contentType = "image/jpeg"
+	}
+	serveObject(c, att.ThumbnailKey, contentType, -1, "inline")
+}
+
+// deleteAttachmentObjects 删除附件在存储中的文件，失败时只会留下孤立文件
+func deleteAttachmentObjects(c *gin.Context, att *model.Attachment) {
+	ctx := c.Request.Context()
+	if err := storage.Delete(ctx, att.StorageKey); err != nil {
+		logger.WarnContext(ctx, "Failed to delete attachment file", "key", att.StorageKey, "error", err)
+	}
+	if att.ThumbnailKey != "" {
+		if err := storage.Delete(ctx, att.ThumbnailKey); err != nil {
+			logger.WarnContext(ctx, "Failed to delete attachment thumbnail", "key", att.ThumbnailKey, "error", err)
+		}
+	}
+}
+
+// destroyAttachment DELETE /api/attachments/:id
+func destroyAttachment(c *gin.Context) {
+	att, ok := findAttachment(c)
+	if !ok {
+		return
+	}
+	if !canModify(c, att.UserID, PermPostModerate) {
+		respondError(c, ErrNotAttachmentOwner)
+		return
+	}
+	if err := GetDB().Unscoped().Delete(att).Error; err != nil {
+		respondError(c, InternalError("Failed to delete attachment", err))
+		return
+	}
+	deleteAttachmentObjects(c, att)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,183 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MaxCommentDepth 评论最大嵌套深度，顶层评论深度为0
+var MaxCommentDepth = 5
+
+var (
+	ErrParentNotFound     = ValidationError("parent_comment_not_found", "parent comment not found")
+	ErrParentDeleted      = ValidationError("parent_comment_deleted", "cannot reply to a deleted comment")
+	ErrCommentTooDeep     = ValidationError("comment_too_deep", "reply depth exceeds limit")
+	ErrParentPostMismatch = ValidationError("parent_post_mismatch", "parent comment belongs to another post")
+)
+
+// CommentNode 评论树节点，已删除的评论只保留结构
+type CommentNode struct {
+	ID        uint           `json:"id"`
+	PostID    int            `json:"post_id"`
+	UserID    int            `json:"user_id,omitempty"`
+	ParentID  *uint          `json:"parent_id"`
+	Depth     int            `json:"depth"`
+	Content   string         `json:"content"`
+	Deleted   bool           `json:"deleted"`
+	CreatedAt time.Time      `json:"created_at"`
+	Replies   []*CommentNode `json:"replies,omitempty"`
+}
+
+// attachParent 校验回复的父评论并设置RootID和Depth
+func attachParent(comment *model.Comment) error {
+	comment.RootID, comment.Depth, comment.Deleted = 0, 0, false
+	if comment.ParentID == nil {
+		return nil
+	}
+	var parent model.Comment
+	if err := GetDB().First(&parent, *comment.ParentID).Error; err != nil {
+		return ErrParentNotFound
+	}
+	if parent.PostID != comment.PostID {
+		return ErrParentPostMismatch
+	}
+	if parent.Deleted {
+		return ErrParentDeleted
+	}
+	if parent.Depth+1 > MaxCommentDepth {
+		return ErrCommentTooDeep
+	}
+	comment.RootID = parent.RootID
+	if comment.RootID == 0 {
+		comment.RootID = parent.ID
+	}
+	comment.Depth = parent.Depth + 1
+	return nil
+}
+
+// removeComment 删除评论：有回复时保留为墓碑，否则直接删除，并顺带清理不再有回复的墓碑父评论
+// 文章的评论数只在评论第一次被删除（变为墓碑或直接删除）时减少
+func removeComment(tx *gorm.DB, comment *model.Comment) error {
+	var replies int64
+	if err := tx.Model(&model.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+		return err
+	}
+	if !comment.Deleted {
+		if err := bumpPostCounter(tx, uint(comment.PostID), "comments_count", -1); err != nil {
+			return err
+		}
+	}
+	if replies > 0 {
+		comment.Deleted = true
+		comment.Content = ""
+		return tx.Save(comment).Error
+	}
+	if err := tx.Delete(comment).Error; err != nil {
+		return err
+	}
+	if comment.ParentID == nil {
+		return nil
+	}
+	var parent model.Comment
+	if err := tx.First(&parent, *comment.ParentID).Error; err != nil || !parent.Deleted {
+		return nil
+	}
+	return removeComment(tx, &parent)
+}
+
+// buildCommentTree 将顶层评论和回复组装成树，replies需要按深度排序
+func buildCommentTree(roots []model.Comment, replies []model.Comment) []*CommentNode {
+	nodes := map[uint]*CommentNode{}
+	toNode := func(cm *model.Comment) *CommentNode {
+		node := &CommentNode{
+			ID:        cm.ID,
+			PostID:    cm.PostID,
+			UserID:    cm.UserID,
+			ParentID:  cm.ParentID,
+			Depth:     cm.Depth,
+			Content:   cm.Content,
+			Deleted:   cm.Deleted,
+			CreatedAt: cm.CreatedAt,
+		}
+		if cm.Deleted {
+			node.UserID = 0
+		}
+		nodes[cm.ID] = node
+		return node
+	}
+
+	tree := make([]*CommentNode, 0, len(roots))
+	for i := range roots {
+		tree = append(tree, toNode(&roots[i]))
+	}
+	for i := range replies {
+		node := toNode(&replies[i])
+		if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+	return tree
+}
+
+// flattenCommentTree 按先序遍历把树展开成带深度的列表
+func flattenCommentTree(tree []*CommentNode) []*CommentNode {
+	var flat []*CommentNode
+	var walk func(nodes []*CommentNode)
+	walk = func(nodes []*CommentNode) {
+		for _, node := range nodes {
+			replies := node.Replies
+			node.Replies = nil
+			flat = append(flat, node)
+			walk(replies)
+		}
+	}
+	walk(tree)
+	return flat
+}
+
+// commentTree GET /api/posts/:id/comments/tree?format=nested|flat
+// 按顶层评论分页，每个顶层评论带上完整的回复树
+func commentTree(c *gin.Context) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "nested")
+	if format != "nested" && format != "flat" {
+		respondError(c, invalidQuery("invalid_query.tree_format"))
+		return
+	}
+
+	q := GetDB().Model(&model.Comment{}).Where("post_id = ? AND parent_id IS NULL", post.ID)
+	res, err := paginate(c, q, commentSorts, "created_at", func(cm *model.Comment) (time.Time, uint) {
+		return cm.CreatedAt, cm.ID
+	})
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+
+	var replies []model.Comment
+	if len(res.Items) > 0 {
+		rootIDs := make([]uint, len(res.Items))
+		for i, root := range res.Items {
+			rootIDs[i] = root.ID
+		}
+		// 按深度排序保证父节点先于子节点出现
+		err := GetDB().Where("root_id IN ?", rootIDs).Order("depth, created_at, id").Find(&replies).Error
+		if err != nil {
+			respondError(c, InternalError("Failed to load replies", err))
+			return
+		}
+	}
+
+	tree := buildCommentTree(res.Items, replies)
+	var data any = tree
+	if format == "flat" {
+		data = flattenCommentTree(tree)
+	}
+	respondPage(c, data, res.Pagination, res.Links)
+}
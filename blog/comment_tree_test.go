@@ -0,0 +1,111 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"testing"
+)
+
+func TestCommentTree(t *testing.T) {
+	r := newTestRouter(t)
+	user, token := loginAs(t, "author", RoleAuthor)
+	post := model.Post{Title: "t", Content: "c", UserID: int(user.ID)}
+	GetDB().Create(&post)
+	base := fmt.Sprintf("/api/posts/%d/comments", post.ID)
+
+	reply := func(parent *uint, content string) uint {
+		t.Helper()
+		w := doRequest(r, http.MethodPost, base, token, CreateCommentRequest{Content: content, ParentID: parent})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %q status = %d: %s", content, w.Code, w.Body)
+		}
+		var cm model.Comment
+		decodeData(w, &cm)
+		return cm.ID
+	}
+	root := reply(nil, "root")
+	child := reply(&root, "child")
+	reply(&child, "grandchild")
+	reply(nil, "second root")
+
+	// 有回复的评论删除后保留为墓碑
+	if w := doRequest(r, http.MethodDelete, fmt.Sprintf("/api/comments/%d", child), token, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", w.Code)
+	}
+
+	w := doRequest(r, http.MethodGet, base+"/tree", token, nil)
+	var nested struct{ Data []*CommentNode }
+	json.Unmarshal(w.Body.Bytes(), &nested)
+	if len(nested.Data) != 2 || nested.Data[0].Content != "root" {
+		t.Fatalf("roots = %+v", nested.Data)
+	}
+	tomb := nested.Data[0].Replies[0]
+	if !tomb.Deleted || tomb.Content != "" || len(tomb.Replies) != 1 || tomb.Replies[0].Content != "grandchild" {
+		t.Fatalf("tombstone = %+v", tomb)
+	}
+
+	w = doRequest(r, http.MethodGet, base+"/tree?format=flat", token, nil)
+	var flat struct{ Data []*CommentNode }
+	json.Unmarshal(w.Body.Bytes(), &flat)
+	var depths []int
+	for _, n := range flat.Data {
+		depths = append(depths, n.Depth)
+	}
+	if fmt.Sprint(depths) != "[0 1 2 0]" {
+		t.Fatalf("flat depths = %v", depths)
+	}
+}
+
+func TestCommentDepthLimit(t *testing.T) {
+	r := newTestRouter(t)
+	old := MaxCommentDepth
+	MaxCommentDepth = 1
+	t.Cleanup(func() { MaxCommentDepth = old })
+	user, token := loginAs(t, "author", RoleAuthor)
+	post := model.Post{Title: "t", Content: "c", UserID: int(user.ID)}
+	GetDB().Create(&post)
+
+	root := model.Comment{Content: "root", PostID: int(post.ID), UserID: int(user.ID)}
+	GetDB().Create(&root)
+	child := model.Comment{Content: "child", PostID: int(post.ID), UserID: int(user.ID), ParentID: &root.ID, RootID: root.ID, Depth: 1}
+	GetDB().Create(&child)
+
+	path := fmt.Sprintf("/api/posts/%d/comments", post.ID)
+	w := doRequest(r, http.MethodPost, path, token, CreateCommentRequest{Content: "too deep", ParentID: &child.ID})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestCommentListHidesTombstoneAuthor(t *testing.T) {
+	r := newTestRouter(t)
+	user, token := loginAs(t, "author", RoleAuthor)
+	post := model.Post{Title: "t", Content: "c", UserID: int(user.ID)}
+	GetDB().Create(&post)
+
+	root := model.Comment{Content: "root", PostID: int(post.ID), UserID: int(user.ID), Deleted: true}
+	GetDB().Create(&root)
+	child := model.Comment{Content: "child", PostID: int(post.ID), UserID: int(user.ID), ParentID: &root.ID, RootID: root.ID, Depth: 1}
+	GetDB().Create(&child)
+
+	base := fmt.Sprintf("/api/posts/%d/comments", post.ID)
+	w := doRequest(r, http.MethodGet, base, token, nil)
+	var list struct{ Data []model.Comment }
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 2 {
+		t.Fatalf("comments = %+v", list.Data)
+	}
+	for _, cm := range list.Data {
+		if cm.Deleted && cm.UserID != 0 {
+			t.Fatalf("tombstone exposes author: %+v", cm)
+		}
+	}
+
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("%s?author=%d", base, user.ID), token, nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0].ID != child.ID {
+		t.Fatalf("author filter = %+v", list.Data)
+	}
+}
@@ -0,0 +1,127 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 创建评论请求结构
+type CreateCommentRequest struct {
+	Content  string `json:"content" binding:"required"`
+	ParentID *uint  `json:"parent_id"` // 回复的评论
+}
+
+// 评论相关错误
+var (
+	ErrCommentNotFound = NotFoundError("comment_not_found", "Comment not found")
+	ErrNotCommentOwner = ForbiddenError("not_comment_owner", "You are not the owner of this comment")
+)
+
+// 评论列表支持的排序字段
+var commentSorts = []string{"created_at", "id"}
+
+// queryComments 按请求参数过滤、排序并分页查询文章的评论
+// 支持参数：page、size、cursor、sort、author、from、to
+// 已删除的墓碑评论清空UserID，与评论树一致
+func queryComments(c *gin.Context, postID int) (*PageResult[model.Comment], error) {
+	q, err := applyCommonFilters(c, GetDB().Model(&model.Comment{}).Where("post_id = ?", postID))
+	if err != nil {
+		return nil, err
+	}
+	// 按作者过滤时不返回墓碑评论，避免暴露作者
+	if c.Query("author") != "" {
+		q = q.Where("deleted = ?", false)
+	}
+	res, err := paginate(c, q, commentSorts, "created_at", func(cm *model.Comment) (time.Time, uint) {
+		return cm.CreatedAt, cm.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.Items {
+		if res.Items[i].Deleted {
+			res.Items[i].UserID = 0
+		}
+	}
+	return res, nil
+}
+
+// listPostComments GET /api/posts/:id/comments
+func listPostComments(c *gin.Context) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	res, err := queryComments(c, int(post.ID))
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	respondPage(c, res.Items, res.Pagination, res.Links)
+}
+
+// storePostComment POST /api/posts/:id/comments
+func storePostComment(c *gin.Context) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	userID, _ := GetCurrentUserID(c)
+	comment := model.Comment{Content: req.Content, PostID: int(post.ID), UserID: userID, ParentID: req.ParentID}
+	if err := attachParent(&comment); err != nil {
+		respondError(c, err)
+		return
+	}
+	err := inTransaction(func(tx *gorm.DB) error {
+		return addComment(tx, &comment)
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to create comment", err))
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/comments/%d", comment.ID))
+	respondData(c, http.StatusCreated, comment)
+}
+
+// addComment 保存评论并增加文章的评论数
+func addComment(tx *gorm.DB, comment *model.Comment) error {
+	if err := tx.Create(comment).Error; err != nil {
+		return err
+	}
+	return bumpPostCounter(tx, uint(comment.PostID), "comments_count", 1)
+}
+
+// destroyComment DELETE /api/comments/:id
+func destroyComment(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+	var comment model.Comment
+	if err := GetDB().First(&comment, id).Error; err != nil {
+		respondError(c, ErrCommentNotFound)
+		return
+	}
+	if !canModify(c, comment.UserID, PermCommentModerate) {
+		respondError(c, ErrNotCommentOwner)
+		return
+	}
+	err := inTransaction(func(tx *gorm.DB) error {
+		return removeComment(tx, &comment)
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to delete comment", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,499 @@
+package blog
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// 环境变量前缀，例如 BLOG_SERVER_ADDR
+const envPrefix = "BLOG_"
+
+// Config 博客服务配置
+// 加载优先级（后者覆盖前者）：默认值 -> 配置文件 -> 环境变量 -> 命令行参数
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Log       LogConfig       `yaml:"log"`
+	Database  DBConfig        `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Storage   StorageConfig   `yaml:"storage"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Mail      MailConfig      `yaml:"mail"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+
+	// RecountCounters 只根据明细表重新计算文章的冗余计数后退出，不启动服务，只能通过 -recount-counters 参数设置
+	RecountCounters bool `yaml:"-"`
+}
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`               // 监听地址
+	Mode              string        `yaml:"mode"`               // gin运行模式：debug、release、test
+	LegacyRoutes      bool          `yaml:"legacy_routes"`      // 是否保留旧的RPC风格路由
+	SchedulerInterval time.Duration `yaml:"scheduler_interval"` // 检查定时发布文章的间隔
+	Locale            string        `yaml:"locale"`             // 默认语言：en-US、zh-CN
+	TrustedProxies    []string      `yaml:"trusted_proxies"`    // 信任其X-Forwarded-For头的代理IP或CIDR，默认不信任任何代理
+}
+
+// JWTConfig JWT配置
+type JWTConfig struct {
+	Algorithm             string        `yaml:"algorithm"`               // 签名算法：HS256、RS256、EdDSA
+	Secret                string        `yaml:"secret"`                  // HS256签名密钥
+	KeyFiles              []string      `yaml:"key_files"`               // RS256/EdDSA的PEM私钥文件，第一个用于签名，其余只用于验证
+	KeyDir                string        `yaml:"key_dir"`                 // RS256/EdDSA的密钥目录，最新的私钥用于签名，轮换出的密钥写入这里
+	RotationInterval      time.Duration `yaml:"rotation_interval"`       // 自动轮换签名密钥的间隔，0表示不轮换，debug以外的模式需要配置key_dir
+	ExpireDuration        time.Duration `yaml:"expire_duration"`         // 访问Token过期时间
+	RefreshExpireDuration time.Duration `yaml:"refresh_expire_duration"` // 刷新Token过期时间
+}
+
+// AuthConfig 账号与权限配置
+type AuthConfig struct {
+	DefaultRole string   `yaml:"default_role"` // 新注册用户的角色
+	AdminEmails []string `yaml:"admin_emails"` // 使用这些邮箱的用户验证邮箱后自动成为管理员
+
+	RequireEmailVerification bool `yaml:"require_email_verification"` // 邮箱验证之前禁止登录
+}
+
+// DefaultConfig 默认配置，与原先硬编码的值保持一致
+func DefaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			Mode:              gin.DebugMode,
+			LegacyRoutes:      true,
+			SchedulerInterval: time.Minute,
+			Locale:            LocaleEnUS,
+		},
+		Log:      DefaultLogConfig(),
+		Database: DefaultDBConfig(),
+		JWT: JWTConfig{
+			Algorithm:             AlgHS256,
+			Secret:                defaultJWTSecret,
+			ExpireDuration:        time.Hour * 24,
+			RefreshExpireDuration: time.Hour * 24 * 7,
+		},
+		Auth: AuthConfig{
+			DefaultRole:              RoleAuthor,
+			RequireEmailVerification: true,
+		},
+		Storage: StorageConfig{
+			Driver:        StorageLocal,
+			LocalDir:      "uploads",
+			MaxUploadSize: 10 << 20,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Driver:  RateLimitMemory,
+			Redis:   RedisConfig{Addr: "localhost:6379"},
+		},
+		Mail: MailConfig{
+			Driver: MailerMemory,
+			From:   "noreply@localhost",
+			AppURL: "http://localhost:8080",
+			SMTP:   SMTPConfig{Port: 587},
+		},
+	}
+}
+
+// LoadConfig 按 默认值 -> 配置文件 -> 环境变量 -> 命令行参数 的顺序加载配置并校验
+// 配置文件路径通过 -config 参数或 BLOG_CONFIG 环境变量指定，支持 .yaml/.yml/.toml
+func LoadConfig(args []string) (*Config, error) {
+	// .env 文件不存在时忽略
+	_ = godotenv.Load()
+
+	cfg := DefaultConfig()
+	fs, configPath := newFlagSet(cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 命令行参数需要最后生效，先记录下来再重新应用
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("flag -%s: %w", name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// newFlagSet 定义命令行参数，参数直接绑定到配置字段上
+func newFlagSet(cfg *Config) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("blog", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径（.yaml/.yml/.toml）")
+	fs.StringVar(&cfg.Server.Addr, "addr", cfg.Server.Addr, "HTTP监听地址")
+	fs.StringVar(&cfg.Server.Mode, "mode", cfg.Server.Mode, "gin运行模式：debug、release、test")
+	fs.BoolVar(&cfg.Server.LegacyRoutes, "legacy-routes", cfg.Server.LegacyRoutes, "是否保留旧的RPC风格路由")
+	fs.DurationVar(&cfg.Server.SchedulerInterval, "scheduler-interval", cfg.Server.SchedulerInterval, "检查定时发布文章的间隔")
+	fs.StringVar(&cfg.Server.Locale, "locale", cfg.Server.Locale, "默认语言：en-US、zh-CN")
+	fs.Var((*stringList)(&cfg.Server.TrustedProxies), "trusted-proxies", "逗号分隔的可信代理IP或CIDR")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "日志级别：debug、info、warn、error")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "日志格式：json、text")
+	fs.StringVar(&cfg.Database.Driver, "db-driver", cfg.Database.Driver, "数据库驱动：mysql、sqlite、postgres")
+	fs.StringVar(&cfg.Database.DSN, "db-dsn", cfg.Database.DSN, "数据库DSN")
+	fs.IntVar(&cfg.Database.MaxOpenConns, "db-max-open-conns", cfg.Database.MaxOpenConns, "最大打开连接数")
+	fs.IntVar(&cfg.Database.MaxIdleConns, "db-max-idle-conns", cfg.Database.MaxIdleConns, "最大空闲连接数")
+	fs.DurationVar(&cfg.Database.ConnMaxLifetime, "db-conn-max-lifetime", cfg.Database.ConnMaxLifetime, "连接最大存活时间")
+	fs.DurationVar(&cfg.Database.ConnMaxIdleTime, "db-conn-max-idle-time", cfg.Database.ConnMaxIdleTime, "连接最大空闲时间")
+	fs.StringVar(&cfg.JWT.Algorithm, "jwt-algorithm", cfg.JWT.Algorithm, "JWT签名算法：HS256、RS256、EdDSA")
+	fs.StringVar(&cfg.JWT.Secret, "jwt-secret", cfg.JWT.Secret, "HS256签名密钥")
+	fs.Var((*stringList)(&cfg.JWT.KeyFiles), "jwt-key-files", "逗号分隔的PEM私钥文件")
+	fs.StringVar(&cfg.JWT.KeyDir, "jwt-key-dir", cfg.JWT.KeyDir, "保存签名密钥的目录")
+	fs.DurationVar(&cfg.JWT.RotationInterval, "jwt-rotation-interval", cfg.JWT.RotationInterval, "签名密钥自动轮换间隔")
+	fs.DurationVar(&cfg.JWT.ExpireDuration, "jwt-expire", cfg.JWT.ExpireDuration, "访问Token过期时间")
+	fs.DurationVar(&cfg.JWT.RefreshExpireDuration, "jwt-refresh-expire", cfg.JWT.RefreshExpireDuration, "刷新Token过期时间")
+	fs.StringVar(&cfg.Auth.DefaultRole, "default-role", cfg.Auth.DefaultRole, "新注册用户的角色")
+	fs.Var((*stringList)(&cfg.Auth.AdminEmails), "admin-emails", "逗号分隔的管理员邮箱")
+	fs.StringVar(&cfg.Storage.Driver, "storage-driver", cfg.Storage.Driver, "附件存储驱动：local、s3")
+	fs.StringVar(&cfg.Storage.LocalDir, "storage-dir", cfg.Storage.LocalDir, "本地附件存储目录")
+	fs.IntVar(&cfg.Storage.MaxUploadSize, "max-upload-size", cfg.Storage.MaxUploadSize, "单个上传文件的最大字节数")
+	fs.BoolVar(&cfg.Auth.RequireEmailVerification, "require-email-verification", cfg.Auth.RequireEmailVerification, "邮箱验证之前禁止登录")
+	fs.StringVar(&cfg.Mail.Driver, "mail-driver", cfg.Mail.Driver, "邮件驱动：smtp、memory")
+	fs.StringVar(&cfg.Mail.AppURL, "app-url", cfg.Mail.AppURL, "邮件中链接的前缀")
+	fs.BoolVar(&cfg.RateLimit.Enabled, "rate-limit", cfg.RateLimit.Enabled, "是否启用限流")
+	fs.StringVar(&cfg.RateLimit.Driver, "rate-limit-driver", cfg.RateLimit.Driver, "限流存储：memory、redis")
+	fs.StringVar(&cfg.RateLimit.Redis.Addr, "redis-addr", cfg.RateLimit.Redis.Addr, "Redis地址")
+	fs.BoolVar(&cfg.RecountCounters, "recount-counters", false, "重新计算文章的点赞、收藏和评论计数后退出")
+	fs.StringVar(&cfg.Metrics.Addr, "metrics-addr", cfg.Metrics.Addr, "Prometheus指标的监听地址，为空表示不启用")
+	return fs, configPath
+}
+
+// loadFile 从YAML或TOML文件加载配置，文件中未出现的字段保留原值
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		// go-toml 不支持 "1h30m" 形式的时长，统一转换成YAML后再解析
+		var raw map[string]any
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(raw); err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension: %q", filepath.Ext(path))
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv 使用 BLOG_ 前缀的环境变量覆盖配置
+func (cfg *Config) loadEnv() error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			*dst = v
+		}
+	}
+	num := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("env %s%s: %w", envPrefix, name, err))
+				return
+			}
+			*dst = n
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("env %s%s: %w", envPrefix, name, err))
+				return
+			}
+			*dst = b
+		}
+	}
+	list := func(name string, dst *[]string) {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			*dst = splitList(v)
+		}
+	}
+	dur := func(name string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(envPrefix + name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("env %s%s: %w", envPrefix, name, err))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	str("SERVER_ADDR", &cfg.Server.Addr)
+	str("SERVER_MODE", &cfg.Server.Mode)
+	boolean("SERVER_LEGACY_ROUTES", &cfg.Server.LegacyRoutes)
+	dur("SERVER_SCHEDULER_INTERVAL", &cfg.Server.SchedulerInterval)
+	str("SERVER_LOCALE", &cfg.Server.Locale)
+	list("SERVER_TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+	str("LOG_LEVEL", &cfg.Log.Level)
+	str("LOG_FORMAT", &cfg.Log.Format)
+	str("DB_DRIVER", &cfg.Database.Driver)
+	str("DB_DSN", &cfg.Database.DSN)
+	num("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	num("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	dur("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	dur("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+	str("JWT_ALGORITHM", &cfg.JWT.Algorithm)
+	str("JWT_SECRET", &cfg.JWT.Secret)
+	list("JWT_KEY_FILES", &cfg.JWT.KeyFiles)
+	str("JWT_KEY_DIR", &cfg.JWT.KeyDir)
+	dur("JWT_ROTATION_INTERVAL", &cfg.JWT.RotationInterval)
+	dur("JWT_EXPIRE_DURATION", &cfg.JWT.ExpireDuration)
+	dur("JWT_REFRESH_EXPIRE_DURATION", &cfg.JWT.RefreshExpireDuration)
+	str("AUTH_DEFAULT_ROLE", &cfg.Auth.DefaultRole)
+	list("AUTH_ADMIN_EMAILS", &cfg.Auth.AdminEmails)
+	str("STORAGE_DRIVER", &cfg.Storage.Driver)
+	str("STORAGE_LOCAL_DIR", &cfg.Storage.LocalDir)
+	num("STORAGE_MAX_UPLOAD_SIZE", &cfg.Storage.MaxUploadSize)
+	str("STORAGE_S3_ENDPOINT", &cfg.Storage.S3.Endpoint)
+	str("STORAGE_S3_REGION", &cfg.Storage.S3.Region)
+	str("STORAGE_S3_BUCKET", &cfg.Storage.S3.Bucket)
+	str("STORAGE_S3_ACCESS_KEY", &cfg.Storage.S3.AccessKey)
+	str("STORAGE_S3_SECRET_KEY", &cfg.Storage.S3.SecretKey)
+	boolean("AUTH_REQUIRE_EMAIL_VERIFICATION", &cfg.Auth.RequireEmailVerification)
+	str("MAIL_DRIVER", &cfg.Mail.Driver)
+	str("MAIL_FROM", &cfg.Mail.From)
+	str("MAIL_APP_URL", &cfg.Mail.AppURL)
+	str("MAIL_SMTP_HOST", &cfg.Mail.SMTP.Host)
+	num("MAIL_SMTP_PORT", &cfg.Mail.SMTP.Port)
+	str("MAIL_SMTP_USERNAME", &cfg.Mail.SMTP.Username)
+	str("MAIL_SMTP_PASSWORD", &cfg.Mail.SMTP.Password)
+	boolean("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	str("RATE_LIMIT_DRIVER", &cfg.RateLimit.Driver)
+	str("RATE_LIMIT_REDIS_ADDR", &cfg.RateLimit.Redis.Addr)
+	str("RATE_LIMIT_REDIS_PASSWORD", &cfg.RateLimit.Redis.Password)
+	num("RATE_LIMIT_REDIS_DB", &cfg.RateLimit.Redis.DB)
+	str("METRICS_ADDR", &cfg.Metrics.Addr)
+	str("METRICS_TOKEN", &cfg.Metrics.Token)
+
+	return errors.Join(errs...)
+}
+
+// Validate 校验配置，返回所有不合法的字段
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	switch cfg.Server.Mode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		errs = append(errs, fmt.Errorf("server.mode must be one of debug, release, test, got %q", cfg.Server.Mode))
+	}
+	if cfg.Server.SchedulerInterval <= 0 {
+		errs = append(errs, errors.New("server.scheduler_interval must be positive"))
+	}
+	if !IsSupportedLocale(cfg.Server.Locale) {
+		errs = append(errs, fmt.Errorf("server.locale must be one of %s, got %q", strings.Join(SupportedLocales, ", "), cfg.Server.Locale))
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if !isValidProxy(proxy) {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies must contain IPs or CIDRs, got %q", proxy))
+		}
+	}
+	if _, err := NewLogger(cfg.Log, io.Discard); err != nil {
+		errs = append(errs, fmt.Errorf("log: %w", err))
+	}
+
+	switch cfg.Database.Driver {
+	case DriverMySQL, DriverSQLite, DriverPostgres:
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be one of mysql, sqlite, postgres, got %q", cfg.Database.Driver))
+	}
+	if cfg.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn is required"))
+	}
+	if cfg.Database.MaxOpenConns < 0 || cfg.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database connection pool sizes must not be negative"))
+	}
+	if cfg.Database.ConnMaxLifetime < 0 || cfg.Database.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("database connection lifetimes must not be negative"))
+	}
+
+	switch cfg.JWT.Algorithm {
+	case AlgHS256:
+		if cfg.JWT.Secret == "" {
+			errs = append(errs, errors.New("jwt.secret is required for HS256"))
+		} else if cfg.Server.Mode == gin.ReleaseMode && cfg.JWT.Secret == defaultJWTSecret {
+			errs = append(errs, errors.New("jwt.secret must be changed from the default in release mode"))
+		}
+		if len(cfg.JWT.KeyFiles) > 0 || cfg.JWT.KeyDir != "" || cfg.JWT.RotationInterval != 0 {
+			errs = append(errs, errors.New("jwt.key_files, jwt.key_dir and jwt.rotation_interval require RS256 or EdDSA"))
+		}
+	case AlgRS256, AlgEdDSA:
+		if cfg.JWT.RotationInterval < 0 {
+			errs = append(errs, errors.New("jwt.rotation_interval must not be negative"))
+		}
+		if len(cfg.JWT.KeyFiles) > 0 && cfg.JWT.KeyDir != "" {
+			errs = append(errs, errors.New("jwt.key_files and jwt.key_dir cannot be used together"))
+		}
+		// 没有密钥目录时生成和轮换的密钥只保存在内存中，重启或多实例部署时已签发的Token会失效
+		if cfg.Server.Mode != gin.DebugMode {
+			if len(cfg.JWT.KeyFiles) == 0 && cfg.JWT.KeyDir == "" {
+				errs = append(errs, errors.New("jwt.key_files or jwt.key_dir is required for RS256 and EdDSA outside debug mode"))
+			}
+			if cfg.JWT.RotationInterval > 0 && cfg.JWT.KeyDir == "" {
+				errs = append(errs, errors.New("jwt.rotation_interval requires jwt.key_dir outside debug mode"))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("jwt.algorithm must be one of HS256, RS256, EdDSA, got %q", cfg.JWT.Algorithm))
+	}
+	if cfg.JWT.ExpireDuration <= 0 {
+		errs = append(errs, errors.New("jwt.expire_duration must be positive"))
+	}
+	if cfg.JWT.RefreshExpireDuration <= cfg.JWT.ExpireDuration {
+		errs = append(errs, errors.New("jwt.refresh_expire_duration must be longer than jwt.expire_duration"))
+	}
+
+	if !IsValidRole(cfg.Auth.DefaultRole) {
+		errs = append(errs, fmt.Errorf("auth.default_role must be one of admin, editor, author, reader, got %q", cfg.Auth.DefaultRole))
+	}
+
+	switch cfg.Storage.Driver {
+	case StorageLocal:
+		if cfg.Storage.LocalDir == "" {
+			errs = append(errs, errors.New("storage.local_dir is required for local storage"))
+		}
+	case StorageS3:
+		s3 := cfg.Storage.S3
+		if s3.Endpoint == "" || s3.Region == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			errs = append(errs, errors.New("storage.s3 requires endpoint, region, bucket, access_key and secret_key"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver must be one of local, s3, got %q", cfg.Storage.Driver))
+	}
+	if cfg.Storage.MaxUploadSize <= 0 {
+		errs = append(errs, errors.New("storage.max_upload_size must be positive"))
+	}
+
+	switch cfg.Mail.Driver {
+	case MailerMemory:
+		// memory驱动不会真正发出邮件，要求验证邮箱时新用户将永远无法登录
+		if cfg.Auth.RequireEmailVerification && cfg.Server.Mode == gin.ReleaseMode {
+			errs = append(errs, errors.New("auth.require_email_verification requires mail.driver smtp in release mode"))
+		}
+	case MailerSMTP:
+		if cfg.Mail.SMTP.Host == "" || cfg.Mail.SMTP.Port <= 0 {
+			errs = append(errs, errors.New("mail.smtp.host and mail.smtp.port are required for smtp"))
+		}
+		if cfg.Mail.From == "" {
+			errs = append(errs, errors.New("mail.from is required for smtp"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver must be one of smtp, memory, got %q", cfg.Mail.Driver))
+	}
+	if !strings.HasPrefix(cfg.Mail.AppURL, "http://") && !strings.HasPrefix(cfg.Mail.AppURL, "https://") {
+		errs = append(errs, fmt.Errorf("mail.app_url must be an http(s) URL, got %q", cfg.Mail.AppURL))
+	}
+
+	switch cfg.RateLimit.Driver {
+	case RateLimitMemory:
+	case RateLimitRedis:
+		if cfg.RateLimit.Redis.Addr == "" {
+			errs = append(errs, errors.New("rate_limit.redis.addr is required for redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("rate_limit.driver must be one of memory, redis, got %q", cfg.RateLimit.Driver))
+	}
+
+	if cfg.Metrics.Addr != "" && cfg.Metrics.Addr == cfg.Server.Addr {
+		errs = append(errs, errors.New("metrics.addr must differ from server.addr"))
+	}
+
+	names := map[string]bool{}
+	for i, p := range cfg.OIDC.Providers {
+		if p.Name == "" || names[p.Name] {
+			errs = append(errs, fmt.Errorf("oidc.providers[%d].name must be unique and not empty", i))
+		}
+		names[p.Name] = true
+		if !strings.HasPrefix(p.Issuer, "http://") && !strings.HasPrefix(p.Issuer, "https://") {
+			errs = append(errs, fmt.Errorf("oidc.providers[%d].issuer must be an http(s) URL, got %q", i, p.Issuer))
+		}
+		if p.ClientID == "" {
+			errs = append(errs, fmt.Errorf("oidc.providers[%d].client_id is required", i))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// isValidProxy 判断可信代理是否是IP或CIDR
+func isValidProxy(proxy string) bool {
+	if strings.Contains(proxy, "/") {
+		_, _, err := net.ParseCIDR(proxy)
+		return err == nil
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+// stringList 逗号分隔的字符串列表参数
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = splitList(v)
+	return nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空白项
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
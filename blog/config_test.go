@@ -0,0 +1,108 @@
+package blog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigLayering(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+database:
+  driver: sqlite
+  dsn: file.db
+jwt:
+  secret: from-file
+  expire_duration: 2h
+`)
+	t.Setenv("BLOG_JWT_SECRET", "from-env")
+	t.Setenv("BLOG_SERVER_ADDR", ":9001")
+
+	cfg, err := LoadConfig([]string{"-config", path, "-addr", ":9002"})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Server.Addr != ":9002" {
+		t.Errorf("addr = %q, want flag value", cfg.Server.Addr)
+	}
+	if cfg.JWT.Secret != "from-env" {
+		t.Errorf("secret = %q, want env value", cfg.JWT.Secret)
+	}
+	if cfg.JWT.ExpireDuration != 2*time.Hour {
+		t.Errorf("expire = %v, want file value", cfg.JWT.ExpireDuration)
+	}
+	if cfg.Database.Driver != DriverSQLite || cfg.Database.DSN != "file.db" {
+		t.Errorf("database = %+v, want file values", cfg.Database)
+	}
+	if cfg.Database.MaxOpenConns != DefaultDBConfig().MaxOpenConns {
+		t.Errorf("max open conns = %d, want default", cfg.Database.MaxOpenConns)
+	}
+	if cfg.RecountCounters {
+		t.Error("recount counters should only run when requested")
+	}
+	cfg, err = LoadConfig([]string{"-recount-counters"})
+	if err != nil || !cfg.RecountCounters {
+		t.Errorf("-recount-counters = %v, %v", cfg, err)
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[database]
+driver = "postgres"
+dsn = "host=localhost"
+conn_max_lifetime = "30m"
+`)
+	cfg, err := LoadConfig([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Database.Driver != DriverPostgres || cfg.Database.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("database = %+v", cfg.Database)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	_, err := LoadConfig([]string{"-mode", "release", "-db-driver", "oracle", "-jwt-expire", "0s", "-trusted-proxies", "10.0.0.0/8,proxy"})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"database.driver", "jwt.secret", "jwt.expire_duration", `server.trusted_proxies must contain IPs or CIDRs, got "proxy"`, "auth.require_email_verification requires mail.driver smtp"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestGeneratedJWTKeysOnlyInDebugMode(t *testing.T) {
+	_, err := LoadConfig([]string{"-mode", "release", "-jwt-algorithm", "EdDSA", "-jwt-rotation-interval", "24h"})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"jwt.key_files or jwt.key_dir is required", "jwt.rotation_interval requires jwt.key_dir"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if _, err := LoadConfig([]string{"-jwt-algorithm", "EdDSA", "-jwt-rotation-interval", "24h"}); err != nil {
+		t.Fatalf("debug mode should allow generated keys: %v", err)
+	}
+	// 密钥目录中的密钥会持久化，可以在release模式下轮换
+	_, err = LoadConfig([]string{"-mode", "release", "-jwt-algorithm", "EdDSA", "-jwt-key-dir", t.TempDir(), "-jwt-rotation-interval", "24h"})
+	if err != nil && strings.Contains(err.Error(), "jwt.") {
+		t.Fatalf("release mode should allow rotation with a key dir: %v", err)
+	}
+}
@@ -1,26 +1,144 @@
 package blog
 
 import (
+	"database/sql"
 	"fmt"
 	model "job/blog/Model"
+	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var db *gorm.DB
 
-func InitDB() error {
-	var err error
-	db, err = gorm.Open(mysql.Open("root:123456@(localhost:3306)/gorm?charset=utf8mb4&parseTime=True&loc=Local"), &gorm.Config{})
+// SlowQueryThreshold 超过这个耗时的SQL记为慢查询
+var SlowQueryThreshold = 200 * time.Millisecond
+
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// DBConfig 数据库配置
+type DBConfig struct {
+	Driver          string        `yaml:"driver"`             // 数据库驱动：mysql、sqlite、postgres
+	DSN             string        `yaml:"dsn"`                // 数据源名称
+	MaxOpenConns    int           `yaml:"max_open_conns"`     // 最大打开连接数，0表示不限制
+	MaxIdleConns    int           `yaml:"max_idle_conns"`     // 最大空闲连接数，0表示使用默认值
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`  // 连接最大存活时间，0表示不限制
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // 连接最大空闲时间，0表示不限制
+}
+
+// DefaultDBConfig 默认数据库配置（本地MySQL）
+func DefaultDBConfig() DBConfig {
+	return DBConfig{
+		Driver:          DriverMySQL,
+		DSN:             "root:123456@(localhost:3306)/gorm?charset=utf8mb4&parseTime=True&loc=Local",
+		MaxOpenConns:    20,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: time.Hour,
+	}
+}
+
+// dialector 根据驱动名称创建对应的GORM方言
+func dialector(cfg DBConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverMySQL:
+		return mysql.Open(cfg.DSN), nil
+	case DriverSQLite:
+		return sqlite.Open(cfg.DSN), nil
+	case DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.Driver)
+	}
+}
+
+// InitDB 按配置连接数据库并执行自动迁移
+func InitDB(cfg DBConfig) error {
+	d, err := dialector(cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := gorm.Open(d, &gorm.Config{
+		// 把各驱动的唯一约束冲突统一转换为gorm.ErrDuplicatedKey
+		TranslateError: true,
+		// SQL日志同样输出到结构化日志，只记录慢查询和错误
+		Logger: gormlogger.NewSlogLogger(logger, gormlogger.Config{
+			SlowThreshold:             SlowQueryThreshold,
+			LogLevel:                  gormlogger.Warn,
+			IgnoreRecordNotFoundError: true,
+			// 日志中的SQL不带参数值，避免记录密码哈希、Token哈希等敏感数据
+			ParameterizedQueries: true,
+		}),
+	})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := conn.DB()
 	if err != nil {
 		return err
 	}
-	err = db.AutoMigrate(&model.User{}, &model.Post{}, &model.Comment{})
+	// 初始化失败时关闭已经打开的连接池
+	if err := setupDB(conn, sqlDB, cfg); err != nil {
+		sqlDB.Close()
+		return err
+	}
+	db = conn
+	logger.Info("Database migrated successfully", "driver", cfg.Driver)
+	return nil
+}
+
+// setupDB 注册插件、设置连接池、迁移表结构并注册连接池指标
+func setupDB(conn *gorm.DB, sqlDB *sql.DB, cfg DBConfig) error {
+	if err := conn.Use(MetricsPlugin{}); err != nil {
+		return err
+	}
+
+	// 连接池设置
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	// 邮箱验证字段出现之前注册的用户视为已验证
+	backfillVerified := !conn.Migrator().HasColumn(&model.User{}, "EmailVerifiedAt")
+	err := conn.AutoMigrate(
+		&model.User{}, &model.Post{}, &model.Comment{}, &model.Tag{}, &model.Category{},
+		&model.RefreshToken{}, &model.RevokedToken{}, &model.PostRevision{},
+		&model.PostLike{}, &model.Bookmark{}, &model.CommentReaction{}, &model.Attachment{},
+		&model.LoginAttempt{}, &model.RecoveryCode{}, &model.UserIdentity{}, &model.PersonalAccessToken{},
+	)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Database migrated successfully")
+	if backfillVerified {
+		err = conn.Model(&model.User{}).Where("email_verified_at IS NULL").
+			UpdateColumn("email_verified_at", gorm.Expr("created_at")).Error
+		if err != nil {
+			return err
+		}
+	}
+	// 迁移成功后才替换连接池指标
+	return setDBStatsCollector(sqlDB, cfg.Driver)
+}
+
+// inTransaction 在数据库事务中执行fn，事务中产生的搜索索引变更在提交成功后才应用，回滚时丢弃
+// 修改文章和评论的事务都应通过这里开启，避免回滚后索引中留下不存在的内容
+func inTransaction(fn func(tx *gorm.DB) error) error {
+	ctx, ops := withSearchOps(GetDB().Statement.Context)
+	if err := GetDB().WithContext(ctx).Transaction(fn); err != nil {
+		return err
+	}
+	ops.apply(ctx)
 	return nil
 }
 
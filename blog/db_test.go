@@ -0,0 +1,88 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupTestDB 使用内存SQLite初始化数据库，供集成测试使用
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	// 内存数据库每个连接相互独立，限制为单连接保证数据可见
+	err := InitDB(DBConfig{Driver: DriverSQLite, DSN: "file::memory:", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("init sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func TestInitDBSQLite(t *testing.T) {
+	setupTestDB(t)
+
+	user := model.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := GetDB().Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	var got model.User
+	if err := GetDB().First(&got, user.ID).Error; err != nil {
+		t.Fatalf("query user: %v", err)
+	}
+	if got.Email != user.Email {
+		t.Fatalf("email = %q, want %q", got.Email, user.Email)
+	}
+}
+
+func TestInitDBUnsupportedDriver(t *testing.T) {
+	if err := InitDB(DBConfig{Driver: "oracle", DSN: "x"}); err == nil {
+		t.Fatal("expected error for unsupported driver")
+	}
+}
+
+func TestInitDBFailedMigrationKeepsCurrentDB(t *testing.T) {
+	setupTestDB(t)
+	current := GetDB()
+
+	// 只读的空数据库可以打开，但迁移会失败
+	path := filepath.Join(t.TempDir(), "ro.db")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitDB(DBConfig{Driver: DriverSQLite, DSN: "file:" + path + "?mode=ro"}); err == nil {
+		t.Fatal("expected migration error on a read-only database")
+	}
+	if GetDB() != current {
+		t.Fatal("failed InitDB replaced the current connection")
+	}
+	if err := GetDB().Create(&model.User{Username: "bob", Email: "bob@example.com", Password: "x"}).Error; err != nil {
+		t.Fatalf("current connection unusable: %v", err)
+	}
+}
+
+func TestSQLLogOmitsValues(t *testing.T) {
+	logs := captureLogs(t)
+	setupTestDB(t)
+
+	user := model.User{Username: "alice", Email: "alice@example.com", Password: "secret-hash"}
+	GetDB().Create(&user)
+	dup := model.User{Username: "alice", Email: "alice@example.com", Password: "secret-hash"}
+	if err := GetDB().Create(&dup).Error; err == nil {
+		t.Fatal("expected duplicate key error")
+	}
+	lines := logs()
+	if len(lines) == 0 {
+		t.Fatal("failed query was not logged")
+	}
+	for _, line := range lines {
+		if strings.Contains(fmt.Sprint(line), "secret-hash") {
+			t.Fatalf("SQL log contains bound values: %v", line)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package blog
+
+import "strings"
+
+// 差异行类型
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffCells 最长公共子序列表的最大规模（约1MB），超过时退化为整体删除加整体插入
+// 任何能读取修订历史的用户都可以请求差异，表的大小需要限制在单次请求可以承受的范围内
+const maxDiffCells = 250_000
+
+// DiffLine 行级差异中的一行，行号从1开始，插入的行没有旧行号，删除的行没有新行号
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// DiffLines 基于最长公共子序列计算两段文本的行级差异
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+
+	// 公共前缀和后缀不需要参与计算
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var out []DiffLine
+	equal := func(i, j int) {
+		out = append(out, DiffLine{Op: DiffEqual, OldLine: i + 1, NewLine: j + 1, Text: x[i]})
+	}
+	for i := 0; i < prefix; i++ {
+		equal(i, i)
+	}
+
+	mx, my := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	n, m := len(mx), len(my)
+	if n*m > maxDiffCells {
+		for i := range mx {
+			out = append(out, DiffLine{Op: DiffDelete, OldLine: prefix + i + 1, Text: mx[i]})
+		}
+		for j := range my {
+			out = append(out, DiffLine{Op: DiffInsert, NewLine: prefix + j + 1, Text: my[j]})
+		}
+	} else {
+		// lcs[i*w+j] 为 mx[i:] 和 my[j:] 的最长公共子序列长度，长度不超过maxDiffCells，用int32即可
+		w := m + 1
+		lcs := make([]int32, (n+1)*w)
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if mx[i] == my[j] {
+					lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+				} else {
+					lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && mx[i] == my[j]:
+				equal(prefix+i, prefix+j)
+				i++
+				j++
+			case i < n && (j == m || lcs[(i+1)*w+j] >= lcs[i*w+j+1]):
+				out = append(out, DiffLine{Op: DiffDelete, OldLine: prefix + i + 1, Text: mx[i]})
+				i++
+			default:
+				out = append(out, DiffLine{Op: DiffInsert, NewLine: prefix + j + 1, Text: my[j]})
+				j++
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		equal(len(x)-suffix+k, len(y)-suffix+k)
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 文章上的冗余计数列，保存文章时忽略，避免覆盖并发的自增自减
+var postCounterColumns = []string{"likes_count", "bookmarks_count", "comments_count"}
+
+// CommentReactions 允许的评论表情回应
+var CommentReactions = []string{"+1", "-1", "laugh", "hooray", "confused", "heart", "rocket", "eyes"}
+
+// ErrUnsupportedReaction 不在CommentReactions中的回应
+var ErrUnsupportedReaction = ValidationError("unsupported_reaction", "Unsupported reaction")
+
+// bumpPostCounter 原子地调整文章的计数列，不触发模型钩子
+func bumpPostCounter(tx *gorm.DB, postID uint, column string, delta int) error {
+	return tx.Model(&model.Post{}).Where("id = ?", postID).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+// toggleRecord 插入或删除一条用户记录，只有记录真正变化时才调整计数，重复请求不会重复计数
+// record为点赞或收藏记录，表中需要有post_id和user_id列
+func toggleRecord(postID, userID uint, record any, on bool, column string) error {
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		var res *gorm.DB
+		if on {
+			res = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		} else {
+			res = tx.Where("post_id = ? AND user_id = ?", postID, userID).Delete(record)
+		}
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		delta := 1
+		if !on {
+			delta = -1
+		}
+		return bumpPostCounter(tx, postID, column, delta)
+	})
+}
+
+// postEngagement 处理点赞和收藏的设置与取消
+func postEngagement(c *gin.Context, on bool, record func(postID, userID uint) any, column, key string) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	id, _ := GetCurrentUserID(c)
+	userID := uint(id)
+	if err := toggleRecord(post.ID, userID, record(post.ID, userID), on, column); err != nil {
+		respondError(c, InternalError("Failed to update "+key, err))
+		return
+	}
+	var count int
+	GetDB().Model(&model.Post{}).Where("id = ?", post.ID).Select(column).Scan(&count)
+	respondData(c, http.StatusOK, gin.H{key: on, column: count})
+}
+
+func newLike(postID, userID uint) any     { return &model.PostLike{PostID: postID, UserID: userID} }
+func newBookmark(postID, userID uint) any { return &model.Bookmark{PostID: postID, UserID: userID} }
+
+// likePost PUT /api/posts/:id/like
+func likePost(c *gin.Context) { postEngagement(c, true, newLike, "likes_count", "liked") }
+
+// unlikePost DELETE /api/posts/:id/like
+func unlikePost(c *gin.Context) { postEngagement(c, false, newLike, "likes_count", "liked") }
+
+// bookmarkPost PUT /api/posts/:id/bookmark
+func bookmarkPost(c *gin.Context) {
+	postEngagement(c, true, newBookmark, "bookmarks_count", "bookmarked")
+}
+
+// unbookmarkPost DELETE /api/posts/:id/bookmark
+func unbookmarkPost(c *gin.Context) {
+	postEngagement(c, false, newBookmark, "bookmarks_count", "bookmarked")
+}
+
+// listBookmarks GET /api/bookmarks
+// 返回当前用户收藏的文章，只包含仍然可见的文章
+func listBookmarks(c *gin.Context) {
+	format, err := contentFormat(c)
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	userID, _ := GetCurrentUserID(c)
+	q := visiblePosts(c, GetDB().Model(&model.Post{})).
+		Where("id IN (?)", GetDB().Model(&model.Bookmark{}).Select("post_id").Where("user_id = ?", userID)).
+		Preload("Tags").Preload("Categories")
+	res, err := paginate(c, q, postSorts, "-created_at", func(p *model.Post) (time.Time, uint) {
+		return p.CreatedAt, p.ID
+	})
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	formatPosts(format, res.Items)
+	respondPage(c, res.Items, res.Pagination, res.Links)
+}
+
+// findReactableComment 查找可以回应的评论，评论所在文章需要对当前用户可见
+func findReactableComment(c *gin.Context) (*model.Comment, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+	var comment model.Comment
+	if err := GetDB().First(&comment, id).Error; err != nil || comment.Deleted {
+		respondError(c, ErrCommentNotFound)
+		return nil, false
+	}
+	if _, err := visiblePost(c, comment.PostID); err != nil {
+		respondError(c, ErrCommentNotFound)
+		return nil, false
+	}
+	return &comment, true
+}
+
+// reactionSummary 统计评论的各种回应数量以及当前用户做出的回应
+func reactionSummary(commentID, userID uint) (gin.H, error) {
+	var rows []struct {
+		Reaction string
+		Count    int64
+	}
+	err := GetDB().Model(&model.CommentReaction{}).Select("reaction, COUNT(*) AS count").
+		Where("comment_id = ?", commentID).Group("reaction").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.Reaction] = row.Count
+	}
+	mine := []string{}
+	err = GetDB().Model(&model.CommentReaction{}).Where("comment_id = ? AND user_id = ?", commentID, userID).
+		Order("reaction").Pluck("reaction", &mine).Error
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"counts": counts, "mine": mine}, nil
+}
+
+// commentReaction 设置或取消当前用户对评论的回应
+func commentReaction(c *gin.Context, on bool) {
+	comment, ok := findReactableComment(c)
+	if !ok {
+		return
+	}
+	reaction := c.Param("reaction")
+	if !slices.Contains(CommentReactions, reaction) {
+		respondError(c, ErrUnsupportedReaction)
+		return
+	}
+	id, _ := GetCurrentUserID(c)
+	record := model.CommentReaction{CommentID: comment.ID, UserID: uint(id), Reaction: reaction}
+	var err error
+	if on {
+		err = GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error
+	} else {
+		err = GetDB().Where("comment_id = ? AND user_id = ? AND reaction = ?", comment.ID, id, reaction).
+			Delete(&model.CommentReaction{}).Error
+	}
+	if err != nil {
+		respondError(c, InternalError("Failed to update reaction", err))
+		return
+	}
+	summary, err := reactionSummary(comment.ID, uint(id))
+	if err != nil {
+		respondError(c, InternalError("Failed to load reactions", err))
+		return
+	}
+	respondData(c, http.StatusOK, summary)
+}
+
+// reactComment PUT /api/comments/:id/reactions/:reaction
+func reactComment(c *gin.Context) { commentReaction(c, true) }
+
+// unreactComment DELETE /api/comments/:id/reactions/:reaction
+func unreactComment(c *gin.Context) { commentReaction(c, false) }
+
+// listCommentReactions GET /api/comments/:id/reactions
+func listCommentReactions(c *gin.Context) {
+	comment, ok := findReactableComment(c)
+	if !ok {
+		return
+	}
+	id, _ := GetCurrentUserID(c)
+	summary, err := reactionSummary(comment.ID, uint(id))
+	if err != nil {
+		respondError(c, InternalError("Failed to load reactions", err))
+		return
+	}
+	respondData(c, http.StatusOK, summary)
+}
+
+// RecountPostCounters 根据明细表重新计算所有文章的冗余计数，用于修复历史数据
+// 会更新整张文章表，通过 -recount-counters 参数在维护时单独执行，不在每次启动时运行
+func RecountPostCounters() error {
+	return GetDB().Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Post{}).UpdateColumns(map[string]any{
+		"likes_count":     gorm.Expr("(SELECT COUNT(*) FROM post_likes WHERE post_likes.post_id = posts.id)"),
+		"bookmarks_count": gorm.Expr("(SELECT COUNT(*) FROM bookmarks WHERE bookmarks.post_id = posts.id)"),
+		"comments_count":  gorm.Expr("(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted = ? AND comments.deleted_at IS NULL)", false),
+	}).Error
+}
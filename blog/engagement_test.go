@@ -0,0 +1,122 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLikesAreIdempotentAndRaceSafe(t *testing.T) {
+	r := newTestRouter(t)
+	author, token := loginAs(t, "author", RoleAuthor)
+	post := model.Post{Title: "t", Content: "c", UserID: int(author.ID)}
+	GetDB().Create(&post)
+	path := fmt.Sprintf("/api/posts/%d/like", post.ID)
+
+	tokens := []string{token}
+	for i := range 7 {
+		_, tk := loginAs(t, fmt.Sprintf("reader%d", i), RoleReader)
+		tokens = append(tokens, tk)
+	}
+	var wg sync.WaitGroup
+	for _, tk := range tokens {
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				doRequest(r, http.MethodPut, path, tk, nil)
+			}()
+		}
+	}
+	wg.Wait()
+
+	w := doRequest(r, http.MethodDelete, path, token, nil)
+	doRequest(r, http.MethodDelete, path, token, nil)
+	var res struct {
+		Liked      bool `json:"liked"`
+		LikesCount int  `json:"likes_count"`
+	}
+	decodeData(w, &res)
+	if w.Code != http.StatusOK || res.Liked || res.LikesCount != 7 {
+		t.Fatalf("unlike = %d %s", w.Code, w.Body)
+	}
+
+	// 编辑文章不能覆盖计数
+	if w := doRequest(r, http.MethodPatch, fmt.Sprintf("/api/posts/%d", post.ID), token, gin.H{"title": "t2"}); w.Code != http.StatusOK {
+		t.Fatalf("patch status = %d", w.Code)
+	}
+	var likes int64
+	GetDB().Model(&model.PostLike{}).Where("post_id = ?", post.ID).Count(&likes)
+	GetDB().First(&post, post.ID)
+	if likes != 7 || post.LikesCount != 7 {
+		t.Fatalf("likes rows = %d, counter = %d", likes, post.LikesCount)
+	}
+}
+
+func TestBookmarksAndCommentCounters(t *testing.T) {
+	r := newTestRouter(t)
+	author, token := loginAs(t, "author", RoleAuthor)
+	seedPosts(t, int(author.ID), "a", "b")
+
+	doRequest(r, http.MethodPut, "/api/posts/2/bookmark", token, nil)
+	if got := titles(getPostPage(t, r, "/api/bookmarks", token).Data); got != "b" {
+		t.Fatalf("bookmarks = %q", got)
+	}
+
+	w := doRequest(r, http.MethodPost, "/api/posts/1/comments", token, gin.H{"content": "root"})
+	var root model.Comment
+	decodeData(w, &root)
+	doRequest(r, http.MethodPost, "/api/posts/1/comments", token, gin.H{"content": "reply", "parent_id": root.ID})
+	// 有回复的评论变为墓碑，计数减一；之后重复的清理不会再减
+	doRequest(r, http.MethodDelete, fmt.Sprintf("/api/comments/%d", root.ID), token, nil)
+	doRequest(r, http.MethodDelete, fmt.Sprintf("/api/comments/%d", root.ID+1), token, nil)
+
+	var post model.Post
+	GetDB().First(&post, 1)
+	if post.CommentsCount != 0 {
+		t.Fatalf("comments_count = %d, want 0", post.CommentsCount)
+	}
+
+	GetDB().Model(&model.Post{}).Where("id = ?", 2).UpdateColumn("bookmarks_count", 9)
+	if err := RecountPostCounters(); err != nil {
+		t.Fatal(err)
+	}
+	post = model.Post{}
+	GetDB().First(&post, 2)
+	if post.BookmarksCount != 1 {
+		t.Fatalf("bookmarks_count after recount = %d", post.BookmarksCount)
+	}
+}
+
+func TestCommentReactions(t *testing.T) {
+	r := newTestRouter(t)
+	author, token := loginAs(t, "author", RoleAuthor)
+	_, readerToken := loginAs(t, "reader", RoleReader)
+	post := model.Post{Title: "t", Content: "c", UserID: int(author.ID)}
+	GetDB().Create(&post)
+	comment := model.Comment{Content: "c", PostID: int(post.ID), UserID: int(author.ID)}
+	GetDB().Create(&comment)
+	base := fmt.Sprintf("/api/comments/%d/reactions", comment.ID)
+
+	doRequest(r, http.MethodPut, base+"/heart", token, nil)
+	doRequest(r, http.MethodPut, base+"/heart", readerToken, nil)
+	doRequest(r, http.MethodPut, base+"/heart", readerToken, nil)
+	doRequest(r, http.MethodPut, base+"/rocket", readerToken, nil)
+	if w := doRequest(r, http.MethodPut, base+"/poop", readerToken, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported reaction status = %d", w.Code)
+	}
+
+	w := doRequest(r, http.MethodGet, base, readerToken, nil)
+	var summary struct {
+		Counts map[string]int64
+		Mine   []string
+	}
+	decodeData(w, &summary)
+	if summary.Counts["heart"] != 2 || summary.Counts["rocket"] != 1 || fmt.Sprint(summary.Mine) != "[heart rocket]" {
+		t.Fatalf("summary = %+v", summary)
+	}
+}
@@ -0,0 +1,320 @@
+package blog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorKind 应用错误的类别，决定HTTP状态码
+// 可以直接用errors.Is(err, KindNotFound)判断错误类别
+type ErrorKind string
+
+const (
+	KindValidation   ErrorKind = "validation"
+	KindUnauthorized ErrorKind = "unauthorized"
+	KindForbidden    ErrorKind = "forbidden"
+	KindNotFound     ErrorKind = "not_found"
+	KindConflict     ErrorKind = "conflict"
+	KindTooLarge     ErrorKind = "too_large"
+	KindUnsupported  ErrorKind = "unsupported_media_type"
+	KindRateLimited  ErrorKind = "rate_limited"
+	KindUnavailable  ErrorKind = "unavailable" // 依赖的外部服务不可用
+	KindInternal     ErrorKind = "internal"
+)
+
+var kindStatus = map[ErrorKind]int{
+	KindValidation:   http.StatusBadRequest,
+	KindUnauthorized: http.StatusUnauthorized,
+	KindForbidden:    http.StatusForbidden,
+	KindNotFound:     http.StatusNotFound,
+	KindConflict:     http.StatusConflict,
+	KindTooLarge:     http.StatusRequestEntityTooLarge,
+	KindUnsupported:  http.StatusUnsupportedMediaType,
+	KindRateLimited:  http.StatusTooManyRequests,
+	KindUnavailable:  http.StatusBadGateway,
+	KindInternal:     http.StatusInternalServerError,
+}
+
+func (k ErrorKind) Error() string { return string(k) }
+
+// Status 类别对应的HTTP状态码
+func (k ErrorKind) Status() int {
+	if status, ok := kindStatus[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// AppError 应用错误，Code是稳定的机器可读错误码，Message是英文说明
+// 返回给客户端时按MessageID（为空时按Code）从消息目录中取对应语言的说明，Args是说明中的参数
+// Err是内部原因，只写日志，不会返回给客户端
+type AppError struct {
+	Kind      ErrorKind
+	Code      string
+	Message   string
+	MessageID string
+	Args      []any
+	Fields    []FieldError
+	Err       error
+}
+
+// FieldError 请求中单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+
+	fe        validator.FieldError // 校验器返回的原始错误，用于翻译
+	messageID string
+	args      []any
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *AppError) Unwrap() error { return e.Err }
+
+// Is 同类别的错误相等，同错误码的错误也相等
+func (e *AppError) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorKind:
+		return e.Kind == t
+	case *AppError:
+		return e.Code == t.Code
+	}
+	return false
+}
+
+// WithCause 返回附带内部原因的副本，预定义的错误可以安全复用
+func (e *AppError) WithCause(err error) *AppError {
+	cp := *e
+	cp.Err = err
+	return &cp
+}
+
+// WithDetail 返回使用消息目录中id对应说明的副本，错误码不变
+func (e *AppError) WithDetail(id string, args ...any) *AppError {
+	cp := *e
+	cp.MessageID = id
+	cp.Args = args
+	cp.Message = translate(LocaleEnUS, id, args...)
+	return &cp
+}
+
+// NewError 创建应用错误
+func NewError(kind ErrorKind, code, message string) *AppError {
+	return &AppError{Kind: kind, Code: code, Message: message}
+}
+
+// 各类别的快捷构造函数
+func ValidationError(code, message string) *AppError {
+	return NewError(KindValidation, code, message)
+}
+
+func UnauthorizedError(code, message string) *AppError {
+	return NewError(KindUnauthorized, code, message)
+}
+
+func ForbiddenError(code, message string) *AppError {
+	return NewError(KindForbidden, code, message)
+}
+
+func NotFoundError(code, message string) *AppError {
+	return NewError(KindNotFound, code, message)
+}
+
+func ConflictError(code, message string) *AppError {
+	return NewError(KindConflict, code, message)
+}
+
+// InternalError 内部错误，客户端只能看到统一的提示，message用于区分出错的操作
+func InternalError(message string, err error) *AppError {
+	return &AppError{Kind: KindInternal, Code: "internal_error", Message: message, Err: err}
+}
+
+// classifyError 已经是AppError的错误原样返回，其余视为内部错误，message说明出错的操作
+func classifyError(err error, message string) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return InternalError(message, err)
+}
+
+// 通用错误
+var (
+	ErrBadRequest   = ValidationError("invalid_request", "Invalid request")
+	ErrUnauthorized = UnauthorizedError("unauthorized", "Authentication is required")
+	ErrForbidden    = ForbiddenError("forbidden", "You do not have permission to perform this action")
+	ErrInvalidID    = ValidationError("invalid_id", "Invalid ID")
+	ErrRouteMissing = NotFoundError("route_not_found", "Route not found")
+	ErrUserNotFound = NotFoundError("user_not_found", "User not found")
+)
+
+// Problem RFC 7807 problem+json响应体，code、errors和request_id是扩展字段
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// ProblemContentType problem+json响应的Content-Type
+const ProblemContentType = "application/problem+json"
+
+// respondError 记录错误并终止后续处理，响应由ErrorHandler统一生成
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// newProblem 按请求的语言生成problem+json响应体，内部错误不暴露具体原因
+func newProblem(c *gin.Context, appErr *AppError) Problem {
+	locale := RequestLocale(c)
+	status := appErr.Kind.Status()
+	return Problem{
+		Type:      "about:blank",
+		Title:     statusTitle(locale, status),
+		Status:    status,
+		Detail:    localizeError(locale, appErr),
+		Instance:  c.Request.URL.Path,
+		Code:      appErr.Code,
+		Errors:    localizeFields(locale, appErr.Fields),
+		RequestID: GetRequestID(c),
+	}
+}
+
+// writeProblem 写入problem+json响应
+func writeProblem(c *gin.Context, err error) {
+	appErr := classifyError(err, "Internal server error")
+	if appErr.Kind == KindInternal && appErr.Err != nil {
+		logger.ErrorContext(c.Request.Context(), appErr.Message, "method", c.Request.Method, "path", c.Request.URL.Path, "error", appErr.Err)
+	}
+	problem := newProblem(c, appErr)
+	c.Header("Content-Type", ProblemContentType)
+	c.Status(problem.Status)
+	_ = json.NewEncoder(c.Writer).Encode(problem)
+}
+
+// ErrorHandler 统一的错误处理中间件，把处理过程中通过respondError记录的错误转换成problem+json响应
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// noRoute 未匹配的路由同样返回problem+json
+func noRoute(c *gin.Context) {
+	respondError(c, ErrRouteMissing)
+}
+
+var registerTagNameOnce sync.Once
+
+// registerJSONFieldNames 校验错误中使用JSON字段名而不是Go字段名
+func registerJSONFieldNames() {
+	registerTagNameOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	})
+}
+
+// BindError 把ShouldBind系列方法返回的错误转换成带字段详情的校验错误
+func BindError(err error) *AppError {
+	appErr := ValidationError("validation_failed", "Request validation failed")
+	var verrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &verrs):
+		for _, fe := range verrs {
+			appErr.Fields = append(appErr.Fields, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: fieldPath(fe) + " " + validationMessage(fe),
+				fe:      fe,
+			})
+		}
+	case errors.As(err, &typeErr):
+		appErr.Fields = []FieldError{{
+			Field:     typeErr.Field,
+			Rule:      "type",
+			Message:   translate(LocaleEnUS, "field_type", typeErr.Field, typeErr.Type.String()),
+			messageID: "field_type",
+			args:      []any{typeErr.Field, typeErr.Type.String()},
+		}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ValidationError("malformed_body", "Request body is not valid JSON").WithCause(err)
+	default:
+		return appErr.WithCause(err)
+	}
+	return appErr
+}
+
+// fieldPath 去掉校验错误字段路径中的结构体名，例如 LoginRequest.email -> email
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		return rest
+	}
+	return fe.Field()
+}
+
+// validationMessage 常用校验规则的英文说明，校验器没有对应翻译时使用
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters long"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters long"
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + fe.Param()
+	case "len":
+		return "must be exactly " + fe.Param() + " characters long"
+	}
+	return "failed on the " + fe.Tag() + " rule"
+}
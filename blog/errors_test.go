@@ -0,0 +1,82 @@
+package blog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAppErrorIs(t *testing.T) {
+	err := fmt.Errorf("load: %w", ErrPostNotFound.WithCause(errors.New("record not found")))
+	if !errors.Is(err, ErrPostNotFound) || !errors.Is(err, KindNotFound) || errors.Is(err, KindForbidden) {
+		t.Fatalf("unexpected errors.Is results for %v", err)
+	}
+	if got := classifyError(errors.New("boom"), "Failed"); got.Kind != KindInternal || got.Kind.Status() != http.StatusInternalServerError {
+		t.Fatalf("classifyError = %+v", got)
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	r := newTestRouter(t)
+	decode := func(w *httptest.ResponseRecorder) Problem {
+		t.Helper()
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ProblemContentType) {
+			t.Fatalf("Content-Type = %q, body %s", ct, w.Body)
+		}
+		var p Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Status != w.Code {
+			t.Fatalf("status %d in body, %d in response", p.Status, w.Code)
+		}
+		return p
+	}
+
+	// 校验错误带上每个字段的规则，字段名使用JSON字段名
+	w := doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "bob", "email": "not-an-email", "password": "123"})
+	p := decode(w)
+	if w.Code != http.StatusBadRequest || p.Code != "validation_failed" || len(p.Errors) != 2 {
+		t.Fatalf("register validation: %d %s", w.Code, w.Body)
+	}
+	if e := p.Errors[0]; e.Field != "email" || e.Rule != "email" || e.Message != "email must be a valid email address" || p.Errors[1].Field != "password" || p.Errors[1].Rule != "min" {
+		t.Fatalf("field errors: %+v", p.Errors)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader("{"))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if p := decode(w); p.Code != "malformed_body" {
+		t.Fatalf("malformed body: %s", w.Body)
+	}
+
+	w = doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "bob", "email": "bob@example.com", "password": "secret1"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body)
+	}
+	w = doRequest(r, http.MethodPost, "/auth/register", "", gin.H{"username": "bob", "email": "bob@example.com", "password": "secret1"})
+	if p := decode(w); w.Code != http.StatusConflict || p.Code != "user_exists" {
+		t.Fatalf("duplicate register: %d %s", w.Code, w.Body)
+	}
+
+	_, token := loginAs(t, "reader", RoleReader)
+	w = doRequest(r, http.MethodGet, "/api/posts/abc", token, nil)
+	if p := decode(w); w.Code != http.StatusBadRequest || p.Code != "invalid_id" || p.Instance != "/api/posts/abc" {
+		t.Fatalf("invalid id: %d %s", w.Code, w.Body)
+	}
+	w = doRequest(r, http.MethodGet, "/api/profile", "", nil)
+	if p := decode(w); w.Code != http.StatusUnauthorized || p.Title != "Unauthorized" {
+		t.Fatalf("missing auth: %d %s", w.Code, w.Body)
+	}
+	w = doRequest(r, http.MethodGet, "/no/such/route", "", nil)
+	if p := decode(w); w.Code != http.StatusNotFound || p.Code != "route_not_found" {
+		t.Fatalf("unknown route: %d %s", w.Code, w.Body)
+	}
+}
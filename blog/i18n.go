@@ -0,0 +1,216 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+	"golang.org/x/text/language"
+)
+
+// 支持的语言
+const (
+	LocaleEnUS = "en-US"
+	LocaleZhCN = "zh-CN"
+)
+
+// SupportedLocales 支持的语言，顺序与localeMatcher一致
+var SupportedLocales = []string{LocaleEnUS, LocaleZhCN}
+
+// DefaultLocale 用户没有设置偏好、Accept-Language也无法匹配时使用的语言
+var DefaultLocale = LocaleEnUS
+
+var localeMatcher = language.NewMatcher([]language.Tag{language.AmericanEnglish, language.SimplifiedChinese})
+
+// ErrUnsupportedLocale 不支持的语言
+var ErrUnsupportedLocale = ValidationError("unsupported_locale", "Unsupported locale")
+
+// IsSupportedLocale 判断是否是支持的语言
+func IsSupportedLocale(locale string) bool {
+	return slices.Contains(SupportedLocales, locale)
+}
+
+// matchLocale 按Accept-Language的权重匹配支持的语言，无法匹配时返回空字符串
+func matchLocale(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+	_, index, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return ""
+	}
+	return SupportedLocales[index]
+}
+
+// userLocale 当前登录用户设置的语言偏好，未登录或未设置时返回空字符串，结果缓存在请求上下文中
+func userLocale(c *gin.Context) string {
+	if v, ok := c.Get("user_locale"); ok {
+		return v.(string)
+	}
+	locale := ""
+	if userID, ok := GetCurrentUserID(c); ok {
+		var user model.User
+		if err := GetDB().Select("locale").First(&user, userID).Error; err == nil {
+			locale = user.Locale
+		}
+	}
+	c.Set("user_locale", locale)
+	return locale
+}
+
+// RequestLocale 确定响应使用的语言：用户偏好优先，其次是Accept-Language，最后是DefaultLocale
+// 第一次确定语言时写入Content-Language和Vary响应头
+func RequestLocale(c *gin.Context) string {
+	if v, ok := c.Get("locale"); ok {
+		return v.(string)
+	}
+	locale := userLocale(c)
+	if !IsSupportedLocale(locale) {
+		locale = matchLocale(c.GetHeader("Accept-Language"))
+	}
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	c.Set("locale", locale)
+	c.Header("Content-Language", locale)
+	c.Header("Vary", "Accept-Language")
+	return locale
+}
+
+// T 返回消息目录中id对应的当前请求语言的说明
+func T(c *gin.Context, id string, args ...any) string {
+	return translate(RequestLocale(c), id, args...)
+}
+
+// lookupMessage 查找指定语言的说明，找不到时使用英文
+func lookupMessage(locale, id string) (string, bool) {
+	if msg, ok := messages[locale][id]; ok {
+		return msg, true
+	}
+	msg, ok := messages[LocaleEnUS][id]
+	return msg, ok
+}
+
+// translate 格式化指定语言的说明，目录中没有id时原样返回id
+func translate(locale, id string, args ...any) string {
+	msg, ok := lookupMessage(locale, id)
+	if !ok {
+		return id
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// localizeError 应用错误在指定语言下的说明，目录中没有时使用错误自带的英文说明
+// 英文目录只收录带参数的说明，内部错误的英文说明保留出错的操作
+func localizeError(locale string, e *AppError) string {
+	id := e.MessageID
+	if id == "" {
+		id = e.Code
+	}
+	msg, ok := messages[locale][id]
+	if !ok {
+		return e.Message
+	}
+	if len(e.Args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, e.Args...)
+}
+
+// statusTitle problem+json的title，英文使用标准的状态码说明
+func statusTitle(locale string, status int) string {
+	if msg, ok := messages[locale]["status."+strconv.Itoa(status)]; ok {
+		return msg
+	}
+	return http.StatusText(status)
+}
+
+var (
+	registerTranslationsOnce sync.Once
+	validatorTranslators     = map[string]ut.Translator{}
+)
+
+// registerValidatorTranslations 为校验器注册各语言的错误说明
+func registerValidatorTranslations() {
+	registerTranslationsOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		uni := ut.New(en.New(), en.New(), zh.New())
+		enTrans, _ := uni.GetTranslator("en")
+		zhTrans, _ := uni.GetTranslator("zh")
+		if err := en_translations.RegisterDefaultTranslations(v, enTrans); err != nil {
+			logger.Error("Failed to register validator translations", "error", err)
+			return
+		}
+		if err := zh_translations.RegisterDefaultTranslations(v, zhTrans); err != nil {
+			logger.Error("Failed to register validator translations", "error", err)
+			return
+		}
+		validatorTranslators[LocaleEnUS] = enTrans
+		validatorTranslators[LocaleZhCN] = zhTrans
+	})
+}
+
+// localizeFields 把字段校验错误翻译成指定语言，校验器没有对应翻译时保留英文说明
+func localizeFields(locale string, fields []FieldError) []FieldError {
+	if len(fields) == 0 {
+		return nil
+	}
+	res := make([]FieldError, len(fields))
+	for i, f := range fields {
+		res[i] = f
+		switch {
+		case f.fe != nil:
+			if trans, ok := validatorTranslators[locale]; ok {
+				if msg := f.fe.Translate(trans); msg != f.fe.Error() {
+					res[i].Message = msg
+				}
+			}
+		case f.messageID != "":
+			res[i].Message = translate(locale, f.messageID, f.args...)
+		}
+	}
+	return res
+}
+
+// 设置语言偏好请求结构，locale为空表示跟随Accept-Language
+type SetLocaleRequest struct {
+	Locale string `json:"locale"`
+}
+
+// setLocale PUT /api/profile/locale
+func setLocale(c *gin.Context) {
+	var req SetLocaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	if req.Locale != "" && !IsSupportedLocale(req.Locale) {
+		respondError(c, ErrUnsupportedLocale.WithDetail("unsupported_locale.locale", req.Locale))
+		return
+	}
+	userID, _ := GetCurrentUserID(c)
+	if err := GetDB().Model(&model.User{}).Where("id = ?", userID).Update("locale", req.Locale).Error; err != nil {
+		respondError(c, InternalError("Failed to update locale", err))
+		return
+	}
+	c.Set("user_locale", req.Locale)
+	c.JSON(http.StatusOK, Envelope{Data: gin.H{"locale": req.Locale}, Message: T(c, "locale_updated")})
+}
@@ -0,0 +1,207 @@
+package blog
+
+import "fmt"
+
+// messages 消息目录：语言 -> 消息ID -> 说明
+// 错误的消息ID默认是错误码，英文说明直接使用错误自带的Message，所以英文目录只收录带参数的说明和成功响应的说明
+// 带参数的说明使用fmt格式，参数顺序在各语言中保持一致
+var messages = map[string]map[string]string{
+	LocaleEnUS: {
+		// 带参数的错误说明
+		"invalid_query.tree_format":        "format must be nested or flat",
+		"invalid_query.format":             "format must be markdown or html",
+		"invalid_query.malformed_cursor":   "malformed cursor",
+		"invalid_query.sort":               "sort must be one of %s, got %q",
+		"invalid_query.date":               "%s must be YYYY-MM-DD or RFC3339",
+		"invalid_query.author":             "author must be a user id",
+		"invalid_query.size":               "size must be between 1 and %d",
+		"invalid_query.page":               "page must be a positive integer",
+		"invalid_query.limit":              "limit must be between 1 and %d",
+		"invalid_query.cursor_sort":        "cursor pagination only supports sorting by created_at or id",
+		"invalid_query.cursor_mismatch":    "cursor does not match sort",
+		"invalid_query.status":             "status must be one of draft, scheduled, published, archived",
+		"invalid_query.revision_range":     "from and to are required",
+		"invalid_query.search_text":        "q is required",
+		"invalid_query.search_type":        "type must be post or comment",
+		"invalid_id.revision":              "Invalid revision number",
+		"invalid_permission.permission":    "Invalid permission: %s",
+		"invalid_status_transition.detail": "status transition is not allowed: %s -> %s",
+		"unknown_category.slug":            "unknown category: %s",
+		"unknown_scope.scope":              "Unknown scope %q",
+		"unsupported_locale.locale":        "Unsupported locale %q",
+		"scope_required.scope":             "Token scope %s is required",
+		"permission_required.permission":   "Permission %s is required",
+		"file_too_large.limit":             "File must not exceed %d bytes",
+		"provider_error.detail":            "Identity provider returned an error: %s",
+		"field_type":                       "%s must be a %s",
+
+		// 成功响应
+		"verification_email_sent": "Verification email sent",
+		"email_verified":          "Email verified",
+		"mail_sent":               "If the email is registered, a message has been sent to it",
+		"password_reset":          "Password has been reset",
+		"logged_out":              "Logged out",
+		"user_role_updated":       "User role updated",
+		"locale_updated":          "Locale updated",
+		"post_details":            "Post details",
+		"post_updated":            "Post updated",
+		"post_deleted":            "Post deleted",
+		"comment_created":         "Comment created",
+		"comment_details":         "Comment details",
+		"comment_deleted":         "Comment deleted",
+		"public_test":             "Public test",
+		"public_test_user":        "test for authenticated user",
+
+		// 邮件，参数依次为用户名、链接有效期、链接
+		"mail.verify_email.subject":   "Verify your email",
+		"mail.verify_email.body":      "Hi %s,\n\nOpen the following link within %s to verify your email:\n\n%s\n\nIf you did not sign up, please ignore this email.\n",
+		"mail.reset_password.subject": "Reset your password",
+		"mail.reset_password.body":    "Hi %s,\n\nWe received a request to reset your password. Open the following link within %s to set a new password:\n\n%s\n\nIf you did not request this, please ignore this email and your password will not change.\n",
+	},
+	LocaleZhCN: {
+		// problem+json的title
+		"status.400": "请求错误",
+		"status.401": "未认证",
+		"status.403": "禁止访问",
+		"status.404": "未找到",
+		"status.409": "冲突",
+		"status.413": "请求体过大",
+		"status.415": "不支持的媒体类型",
+		"status.429": "请求过多",
+		"status.500": "服务器内部错误",
+		"status.502": "网关错误",
+
+		// 通用错误
+		"internal_error":     "服务器内部错误，请稍后重试",
+		"invalid_request":    "请求不合法",
+		"validation_failed":  "请求参数校验失败",
+		"malformed_body":     "请求体不是合法的JSON",
+		"unauthorized":       "需要登录",
+		"forbidden":          "没有权限执行此操作",
+		"invalid_id":         "ID不合法",
+		"route_not_found":    "路由不存在",
+		"user_not_found":     "用户不存在",
+		"rate_limited":       "请求过于频繁，请稍后再试",
+		"unsupported_locale": "不支持的语言",
+		"field_type":         "%s必须是%s类型",
+
+		// 认证
+		"missing_authorization":  "缺少Authorization请求头",
+		"missing_token":          "缺少Token",
+		"invalid_token":          "Token无效或已过期",
+		"token_revoked":          "Token已被吊销",
+		"mfa_required":           "需要完成两步验证",
+		"invalid_credentials":    "邮箱或密码错误",
+		"login_locked":           "登录失败次数过多，请稍后再试",
+		"email_not_verified":     "邮箱尚未验证",
+		"user_exists":            "用户已存在",
+		"invalid_refresh_token":  "刷新Token无效",
+		"refresh_token_expired":  "刷新Token已过期",
+		"refresh_token_reused":   "检测到刷新Token被重复使用，会话已吊销",
+		"invalid_action_token":   "链接无效或已过期",
+		"action_token_used":      "链接已被使用",
+		"invalid_code":           "验证码错误",
+		"invalid_challenge":      "两步验证请求无效或已过期",
+		"totp_enabled":           "两步验证已经启用",
+		"totp_not_enrolled":      "尚未登记两步验证",
+		"totp_not_enabled":       "两步验证未启用",
+		"invalid_personal_token": "个人访问Token无效、已过期或已吊销",
+		"token_not_found":        "Token不存在",
+		"session_required":       "个人访问Token不能访问此接口",
+		"scope_required":         "Token缺少所需的授权范围",
+		"unknown_scope":          "未知的授权范围",
+		"permission_required":    "缺少所需的权限",
+		"invalid_role":           "角色不合法",
+		"invalid_permission":     "权限不合法",
+
+		// OIDC登录
+		"unknown_provider":     "未知的身份提供方",
+		"invalid_login_state":  "登录状态无效或已过期",
+		"provider_error":       "身份提供方返回了错误",
+		"provider_unavailable": "身份提供方暂时不可用",
+		"code_exchange_failed": "授权码换取失败",
+		"invalid_id_token":     "ID Token无效",
+		"email_unverified":     "身份提供方没有返回已验证的邮箱",
+		"account_unverified":   "使用该邮箱的账号尚未验证，请先验证邮箱或重置密码后再通过身份提供方登录",
+
+		// 文章、评论和附件
+		"invalid_query":             "查询参数不合法",
+		"post_not_found":            "文章不存在",
+		"not_post_owner":            "你不是这篇文章的作者",
+		"invalid_post_status":       "状态必须是draft、scheduled、published、archived之一",
+		"invalid_status_transition": "不允许的状态变更",
+		"publish_at_required":       "定时发布的文章必须设置publish_at",
+		"publish_at_not_in_future":  "publish_at必须是将来的时间",
+		"publish_at_not_scheduled":  "只有定时发布的文章可以设置publish_at",
+		"invalid_tag":               fmt.Sprintf("标签长度必须为1-%d个字符", MaxTagLength),
+		"too_many_tags":             fmt.Sprintf("每篇文章最多%d个标签", MaxTagsPerPost),
+		"unknown_category":          "分类不存在",
+		"category_exists":           "分类已存在",
+		"revision_not_found":        "版本不存在",
+		"comment_not_found":         "评论不存在",
+		"not_comment_owner":         "你不是这条评论的作者",
+		"parent_comment_not_found":  "回复的评论不存在",
+		"parent_post_mismatch":      "回复的评论属于其他文章",
+		"parent_comment_deleted":    "不能回复已删除的评论",
+		"comment_too_deep":          "回复层级超过限制",
+		"unsupported_reaction":      "不支持的回应",
+		"file_too_large":            "文件过大",
+		"file_required":             "缺少文件",
+		"file_unreadable":           "读取文件失败",
+		"unsupported_file_type":     "不支持的文件类型",
+		"attachment_not_found":      "附件不存在",
+		"file_not_found":            "文件不存在",
+		"thumbnail_not_found":       "附件没有缩略图",
+		"not_attachment_owner":      "你不是这个附件的上传者",
+
+		// 带参数的错误说明
+		"invalid_query.tree_format":        "format必须是nested或flat",
+		"invalid_query.format":             "format必须是markdown或html",
+		"invalid_query.malformed_cursor":   "游标格式不正确",
+		"invalid_query.sort":               "sort必须是%s之一，实际为%q",
+		"invalid_query.date":               "%s必须是YYYY-MM-DD或RFC3339格式",
+		"invalid_query.author":             "author必须是用户ID",
+		"invalid_query.size":               "size必须在1到%d之间",
+		"invalid_query.page":               "page必须是正整数",
+		"invalid_query.limit":              "limit必须在1到%d之间",
+		"invalid_query.cursor_sort":        "游标分页只支持按created_at或id排序",
+		"invalid_query.cursor_mismatch":    "游标与排序方式不匹配",
+		"invalid_query.status":             "status必须是draft、scheduled、published、archived之一",
+		"invalid_query.revision_range":     "from和to不能为空",
+		"invalid_query.search_text":        "q不能为空",
+		"invalid_query.search_type":        "type必须是post或comment",
+		"invalid_id.revision":              "版本号不合法",
+		"invalid_permission.permission":    "权限不合法：%s",
+		"invalid_status_transition.detail": "不允许的状态变更：%s -> %s",
+		"unknown_category.slug":            "分类不存在：%s",
+		"unknown_scope.scope":              "未知的授权范围%q",
+		"unsupported_locale.locale":        "不支持的语言%q",
+		"scope_required.scope":             "Token缺少授权范围%s",
+		"permission_required.permission":   "缺少权限%s",
+		"file_too_large.limit":             "文件不能超过%d字节",
+		"provider_error.detail":            "身份提供方返回了错误：%s",
+
+		// 成功响应
+		"verification_email_sent": "验证邮件已发送",
+		"email_verified":          "邮箱验证成功",
+		"mail_sent":               "如果该邮箱已注册，邮件已经发送",
+		"password_reset":          "密码已重置",
+		"logged_out":              "已退出登录",
+		"user_role_updated":       "用户角色已更新",
+		"locale_updated":          "语言偏好已更新",
+		"post_details":            "文章详情",
+		"post_updated":            "文章已更新",
+		"post_deleted":            "文章已删除",
+		"comment_created":         "评论已创建",
+		"comment_details":         "评论详情",
+		"comment_deleted":         "评论已删除",
+		"public_test":             "公开测试",
+		"public_test_user":        "已登录用户的测试",
+
+		// 邮件
+		"mail.verify_email.subject":   "验证你的邮箱",
+		"mail.verify_email.body":      "你好 %s：\n\n请在%s内打开以下链接完成邮箱验证：\n\n%s\n\n如果不是你本人注册，请忽略这封邮件。\n",
+		"mail.reset_password.subject": "重置你的密码",
+		"mail.reset_password.body":    "你好 %s：\n\n我们收到了重置密码的请求，请在%s内打开以下链接设置新密码：\n\n%s\n\n如果不是你本人操作，请忽略这封邮件，密码不会改变。\n",
+	},
+}
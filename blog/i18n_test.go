@@ -0,0 +1,155 @@
+package blog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMessageCatalog 每个错误码和英文目录中的说明都要有中文翻译，参数个数保持一致
+func TestMessageCatalog(t *testing.T) {
+	zhCN := messages[LocaleZhCN]
+	for id, msg := range messages[LocaleEnUS] {
+		zh, ok := zhCN[id]
+		if !ok {
+			t.Errorf("%s: missing zh-CN translation", id)
+		} else if strings.Count(zh, "%") != strings.Count(msg, "%") {
+			t.Errorf("%s: argument count differs between %q and %q", id, msg, zh)
+		}
+	}
+
+	// 从源码中找出所有错误构造函数使用的错误码
+	constructors := map[string]int{"ValidationError": 0, "UnauthorizedError": 0, "ForbiddenError": 0, "NotFoundError": 0, "ConflictError": 0, "NewError": 1}
+	files, _ := filepath.Glob("*.go")
+	fset := token.NewFileSet()
+	codes := 0
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			pos, ok := constructors[fn.Name]
+			if !ok || len(call.Args) <= pos {
+				return true
+			}
+			lit, ok := call.Args[pos].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			code, _ := strconv.Unquote(lit.Value)
+			codes++
+			if _, ok := zhCN[code]; !ok {
+				t.Errorf("%s: error code %q has no zh-CN translation", fset.Position(lit.Pos()), code)
+			}
+			return true
+		})
+	}
+	if codes == 0 {
+		t.Fatal("no error codes found")
+	}
+}
+
+func TestMatchLocale(t *testing.T) {
+	cases := map[string]string{
+		"":                        "",
+		"zh-CN,zh;q=0.9,en;q=0.8": LocaleZhCN,
+		"zh":                      LocaleZhCN,
+		"en-GB,en;q=0.9":          LocaleEnUS,
+		"fr-FR,zh;q=0.5":          LocaleZhCN,
+		"fr":                      "",
+	}
+	for header, want := range cases {
+		if got := matchLocale(header); got != want {
+			t.Errorf("matchLocale(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestLocalizedResponses(t *testing.T) {
+	r := newTestRouter(t)
+	request := func(method, path, token, lang string, body any) (*httptest.ResponseRecorder, Problem) {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", lang)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var p Problem
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return w, p
+	}
+
+	// 字段校验错误使用校验器的翻译
+	w, p := request(http.MethodPost, "/auth/register", "", "zh-CN,zh;q=0.9", gin.H{"username": "bob", "email": "bob"})
+	if w.Header().Get("Content-Language") != LocaleZhCN || p.Title != "请求错误" || p.Detail != "请求参数校验失败" {
+		t.Fatalf("zh-CN validation: %s %s", w.Header(), w.Body)
+	}
+	fields := map[string]string{}
+	for _, f := range p.Errors {
+		fields[f.Field] = f.Message
+	}
+	if fields["email"] != "email必须是一个有效的邮箱" || fields["password"] != "password为必填字段" {
+		t.Fatalf("zh-CN field errors: %v", fields)
+	}
+
+	// 带参数的说明
+	_, token := loginAs(t, "reader", RoleReader)
+	if _, p := request(http.MethodGet, "/api/posts?size=1000", token, "zh", nil); p.Detail != fmt.Sprintf("size必须在1到%d之间", MaxPageSize) {
+		t.Fatalf("zh-CN detail with args: %+v", p)
+	}
+	if _, p := request(http.MethodGet, "/api/posts?size=1000", token, "en", nil); p.Detail != fmt.Sprintf("size must be between 1 and %d", MaxPageSize) {
+		t.Fatalf("en-US detail with args: %+v", p)
+	}
+
+	// 用户偏好优先于Accept-Language
+	if w, _ := request(http.MethodPut, "/api/profile/locale", token, "en", gin.H{"locale": "fr-FR"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported locale: %d", w.Code)
+	}
+	w, _ = request(http.MethodPut, "/api/profile/locale", token, "en", gin.H{"locale": LocaleZhCN})
+	var res struct {
+		Data    struct{ Locale string }
+		Message string
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || res.Message != "语言偏好已更新" {
+		t.Fatalf("set locale: %d %s", w.Code, w.Body)
+	}
+	if w, p := request(http.MethodGet, "/api/posts/999", token, "en-US", nil); w.Code != http.StatusNotFound || p.Detail != "文章不存在" {
+		t.Fatalf("user preference: %d %s", w.Code, w.Body)
+	}
+
+	// 没有Accept-Language时使用默认语言
+	if w, p := request(http.MethodGet, "/no/such/route", "", "", nil); w.Header().Get("Content-Language") != DefaultLocale || p.Detail != "Route not found" {
+		t.Fatalf("default locale: %s %s", w.Header(), w.Body)
+	}
+}
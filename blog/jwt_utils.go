@@ -2,46 +2,101 @@ package blog
 
 import (
 	"errors"
+	model "job/blog/Model"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// 默认JWT密钥，仅用于本地开发
+const defaultJWTSecret = "jss@13&^()"
+
+// 访问Token的sub，同一密钥签发的其他用途的Token不能当作访问Token使用
+const accessTokenSubject = "user-token"
+
 // JWT配置
 var (
-	JWTSecret           = []byte("jss@13&^()") // 生产环境中应该使用环境变量
-	TokenExpireDuration = time.Hour * 24       // Token过期时间：24小时
+	JWTSecret                  = []byte(defaultJWTSecret) // 生产环境中通过配置注入
+	TokenExpireDuration        = time.Hour * 24           // Token过期时间：24小时
+	RefreshTokenExpireDuration = time.Hour * 24 * 7       // 刷新Token过期时间：7天
 )
 
+// InitJWT 使用配置初始化签名密钥和过期时间
+func InitJWT(cfg JWTConfig) error {
+	JWTSecret = []byte(cfg.Secret)
+	TokenExpireDuration = cfg.ExpireDuration
+	RefreshTokenExpireDuration = cfg.RefreshExpireDuration
+
+	ks := newHMACKeySet(JWTSecret)
+	if cfg.Algorithm != "" && cfg.Algorithm != AlgHS256 {
+		var err error
+		if cfg.KeyDir != "" {
+			ks, err = newDirKeySet(cfg.Algorithm, cfg.KeyDir, TokenExpireDuration)
+		} else {
+			ks, err = newKeySet(cfg.Algorithm, cfg.KeyFiles)
+		}
+		if err != nil {
+			return err
+		}
+		if cfg.KeyDir == "" && (len(cfg.KeyFiles) == 0 || cfg.RotationInterval > 0) {
+			logger.Warn("JWT keys are generated in memory, tokens become invalid after restart; configure jwt.key_dir to persist them")
+		}
+		if cfg.RotationInterval > 0 {
+			ks.StartRotation(cfg.RotationInterval)
+		}
+	}
+	swapKeySet(ks).StopRotation()
+	return nil
+}
+
 // 自定义Claims结构
 type CustomClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	UserID      uint     `json:"user_id"`
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	FamilyID    string   `json:"fid,omitempty"` // 所属刷新Token家族，登出时整个家族一起吊销
+	MFA         bool     `json:"mfa,omitempty"` // 会话是否完成了两步验证
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint, username, email string) (string, error) {
+// GenerateToken 生成JWT Token，familyID为对应刷新Token的家族ID
+func GenerateToken(user *model.User, familyID string) (string, error) {
+	return generateToken(user, familyID, false)
+}
+
+// generateToken 生成JWT Token，mfa表示会话是否完成了两步验证
+func generateToken(user *model.User, familyID string, mfa bool) (string, error) {
 	// 创建Claims
 	claims := CustomClaims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: UserPermissions(user),
+		FamilyID:    familyID,
+		MFA:         mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpireDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "jss",
-			Subject:   "user-token",
+			Subject:   accessTokenSubject,
 		},
 	}
 
-	// 创建Token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// 创建Token，通过kid标明签名密钥
+	key := currentKeySet().signingKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
 	// 签名Token
-	tokenString, err := token.SignedString(JWTSecret)
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -53,11 +108,16 @@ func GenerateToken(userID uint, username, email string) (string, error) {
 func ParseToken(tokenString string) (*CustomClaims, error) {
 	// 解析Token
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (any, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := currentKeySet().verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		// 验证签名方法，防止算法混淆攻击
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return JWTSecret, nil
+		return key.Public, nil
 	})
 
 	if err != nil {
@@ -65,25 +125,9 @@ func ParseToken(tokenString string) (*CustomClaims, error) {
 	}
 
 	// 验证Token并提取Claims
-	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid && claims.Subject == accessTokenSubject {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
-
-// RefreshToken 刷新Token
-func RefreshToken(tokenString string) (string, error) {
-	claims, err := ParseToken(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	// 检查Token是否即将过期（剩余时间少于1小时）
-	if time.Until(claims.ExpiresAt.Time) > time.Hour {
-		return "", errors.New("token doesn't need refresh yet")
-	}
-
-	// 生成新Token
-	return GenerateToken(claims.UserID, claims.Username, claims.Email)
-}
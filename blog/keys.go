@@ -0,0 +1,465 @@
+package blog
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// 自动生成RSA密钥的位数
+const rsaKeyBits = 2048
+
+// KeyReloadInterval 使用密钥目录时重新读取目录的间隔，多个实例共享目录时据此发现其他实例轮换出的密钥
+var KeyReloadInterval = time.Minute
+
+// SigningKey 签名密钥，通过kid标识
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.PrivateKey // HS256时为[]byte
+	Public    crypto.PublicKey  // HS256时为[]byte
+	ExpiresAt time.Time         // 轮换后仅用于验证的截止时间，零值表示不过期
+	CreatedAt time.Time         // 生成时间，密钥目录中为文件的修改时间，用于判断是否需要轮换
+
+	file string // 密钥目录中的文件，轮换后过期时删除
+}
+
+// JWK JSON Web Key（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// KeySet 当前用于签名的密钥及仍可用于验证的历史密钥
+type KeySet struct {
+	mu      sync.RWMutex
+	alg     string
+	current *SigningKey
+	keys    map[string]*SigningKey
+	dir     string // 密钥目录，为空时轮换出的密钥只保存在内存中
+	stop    chan struct{}
+}
+
+// 全局密钥集合，由InitJWT初始化，通过currentKeySet读取
+var (
+	keySetMu sync.RWMutex
+	keySet   = newHMACKeySet(JWTSecret)
+)
+
+// currentKeySet 返回当前的全局密钥集合
+func currentKeySet() *KeySet {
+	keySetMu.RLock()
+	defer keySetMu.RUnlock()
+	return keySet
+}
+
+// swapKeySet 替换全局密钥集合，返回之前的密钥集合
+func swapKeySet(ks *KeySet) *KeySet {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+	old := keySet
+	keySet = ks
+	return old
+}
+
+// newHMACKeySet 使用对称密钥创建密钥集合，与原先的HS256行为一致
+func newHMACKeySet(secret []byte) *KeySet {
+	key := &SigningKey{Method: jwt.SigningMethodHS256, Private: secret, Public: secret}
+	return &KeySet{alg: AlgHS256, current: key, keys: map[string]*SigningKey{"": key}}
+}
+
+// newKeySet 根据算法和私钥文件创建密钥集合
+// 第一个私钥文件用于签名，其余只用于验证；没有私钥文件时自动生成一个（只保存在内存中，仅用于debug模式）
+func newKeySet(alg string, files []string) (*KeySet, error) {
+	ks := &KeySet{alg: alg, keys: map[string]*SigningKey{}}
+	for _, file := range files {
+		key, err := loadSigningKey(alg, file)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[key.ID] = key
+		if ks.current == nil {
+			ks.current = key
+		}
+	}
+	if ks.current == nil {
+		key, err := generateSigningKey(alg)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[key.ID] = key
+		ks.current = key
+	}
+	return ks, nil
+}
+
+// newDirKeySet 从密钥目录创建密钥集合，目录中没有密钥时生成一个并写入目录
+// 最新的密钥用于签名，其余密钥在下一个密钥生成后的retain时间内仍可用于验证
+func newDirKeySet(alg, dir string, retain time.Duration) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create key dir: %w", err)
+	}
+	ks := &KeySet{alg: alg, keys: map[string]*SigningKey{}, dir: dir}
+	if err := ks.reload(retain); err != nil {
+		return nil, err
+	}
+	if ks.current == nil {
+		key, err := generateSigningKey(alg)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeKeyFile(dir, key); err != nil {
+			return nil, err
+		}
+		ks.keys[key.ID] = key
+		ks.current = key
+	}
+	return ks, nil
+}
+
+// loadKeyDir 读取目录中的所有.pem私钥，按生成时间从旧到新排序
+func loadKeyDir(alg, dir string) ([]*SigningKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*SigningKey, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat key file: %w", err)
+		}
+		key, err := loadSigningKey(alg, file)
+		if err != nil {
+			return nil, err
+		}
+		key.CreatedAt, key.file = info.ModTime(), file
+		keys = append(keys, key)
+	}
+	// 修改时间相同时按文件名排序，写入的文件名以纳秒级的生成时间开头
+	sort.SliceStable(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].file < keys[j].file
+	})
+	return keys, nil
+}
+
+// reload 重新读取密钥目录，其他实例轮换出的新密钥由此生效，已过期的旧密钥被忽略
+func (ks *KeySet) reload(retain time.Duration) error {
+	files, err := loadKeyDir(ks.alg, ks.dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	now := time.Now()
+	keys := map[string]*SigningKey{}
+	for i, key := range files {
+		if i < len(files)-1 {
+			key.ExpiresAt = files[i+1].CreatedAt.Add(retain)
+			if now.After(key.ExpiresAt) {
+				continue
+			}
+		}
+		keys[key.ID] = key
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	ks.current = files[len(files)-1]
+	return nil
+}
+
+// writeKeyFile 把私钥以PKCS#8 PEM格式写入密钥目录，先写临时文件再改名，其他实例不会读到写了一半的文件
+func writeKeyFile(dir string, key *SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".key-*")
+	if err != nil {
+		return fmt.Errorf("write key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := pem.Encode(tmp, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write key file: %w", err)
+	}
+	name := key.CreatedAt.UTC().Format("20060102T150405.000000000Z") + "-" + key.ID[:8] + ".pem"
+	file := filepath.Join(dir, name)
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("write key file: %w", err)
+	}
+	key.file = file
+	return nil
+}
+
+// loadSigningKey 从PEM文件加载私钥
+func loadSigningKey(alg, file string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	var private crypto.PrivateKey
+	switch alg {
+	case AlgRS256:
+		private, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case AlgEdDSA:
+		private, err = jwt.ParseEdPrivateKeyFromPEM(data)
+	default:
+		err = fmt.Errorf("algorithm %s does not use key files", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load key file %s: %w", file, err)
+	}
+	return newSigningKey(alg, private)
+}
+
+// generateSigningKey 生成新的私钥
+func generateSigningKey(alg string) (*SigningKey, error) {
+	var private crypto.PrivateKey
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("cannot generate key for algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSigningKey(alg, private)
+}
+
+// newSigningKey 由私钥构造签名密钥，kid取公钥的JWK指纹
+func newSigningKey(alg string, private crypto.PrivateKey) (*SigningKey, error) {
+	key := &SigningKey{Private: private}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.Public = k.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+	if key.Method.Alg() != alg {
+		return nil, fmt.Errorf("key type %T does not match algorithm %s", private, alg)
+	}
+	jwk := key.JWK()
+	key.ID = jwkThumbprint(jwk)
+	key.CreatedAt = time.Now()
+	return key, nil
+}
+
+// JWK 返回公钥的JWK表示
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// jwkThumbprint 计算JWK指纹（RFC 7638），成员按字典序排列
+func jwkThumbprint(jwk JWK) string {
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// signingKey 返回当前用于签名的密钥
+func (ks *KeySet) signingKey() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.current
+}
+
+// verificationKey 根据kid查找验证密钥，已过期的历史密钥不再接受
+func (ks *KeySet) verificationKey(kid string) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt) {
+		return nil, fmt.Errorf("key %q has been retired", kid)
+	}
+	return key, nil
+}
+
+// Rotate 生成新的签名密钥，旧密钥在retain时间内仍可用于验证已签发的Token
+// 使用密钥目录时新密钥先写入目录再用于签名，过期的旧密钥文件随之删除
+func (ks *KeySet) Rotate(retain time.Duration) error {
+	if ks.alg == AlgHS256 {
+		return errors.New("HS256 keys cannot be rotated automatically")
+	}
+	key, err := generateSigningKey(ks.alg)
+	if err != nil {
+		return err
+	}
+	if ks.dir != "" {
+		if err := writeKeyFile(ks.dir, key); err != nil {
+			return err
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	now := time.Now()
+	for kid, old := range ks.keys {
+		if !old.ExpiresAt.IsZero() && now.After(old.ExpiresAt) {
+			delete(ks.keys, kid)
+			if old.file != "" {
+				if err := os.Remove(old.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+					logger.Warn("Failed to remove retired JWT key", "file", old.file, "error", err)
+				}
+			}
+		}
+	}
+	ks.current.ExpiresAt = now.Add(retain)
+	ks.keys[key.ID] = key
+	ks.current = key
+	return nil
+}
+
+// rotateIfDue 当前签名密钥使用超过interval时轮换
+// 使用密钥目录时先重新读取目录，其他实例已经轮换出新密钥时直接使用，不再重复生成
+func (ks *KeySet) rotateIfDue(interval, retain time.Duration) error {
+	if ks.dir != "" {
+		if err := ks.reload(retain); err != nil {
+			return err
+		}
+	}
+	if time.Since(ks.signingKey().CreatedAt) < interval {
+		return nil
+	}
+	return ks.Rotate(retain)
+}
+
+// StartRotation 启动定时轮换，旧密钥保留到其签发的Token全部过期
+// 使用密钥目录时每KeyReloadInterval检查一次目录
+func (ks *KeySet) StartRotation(interval time.Duration) {
+	ks.StopRotation()
+	stop := make(chan struct{})
+	ks.mu.Lock()
+	ks.stop = stop
+	ks.mu.Unlock()
+
+	tick := interval
+	if ks.dir != "" {
+		tick = min(interval, KeyReloadInterval)
+	}
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := ks.rotateIfDue(interval, TokenExpireDuration); err != nil {
+					logger.Error("JWT key rotation failed", "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopRotation 停止定时轮换
+func (ks *KeySet) StopRotation() {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.stop != nil {
+		close(ks.stop)
+		ks.stop = nil
+	}
+}
+
+// JWKS 返回所有仍有效的公钥，HS256密钥不对外公开
+func (ks *KeySet) JWKS() []JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	keys := []JWK{}
+	if ks.alg == AlgHS256 {
+		return keys
+	}
+	now := time.Now()
+	for _, key := range ks.keys {
+		if key.ExpiresAt.IsZero() || now.Before(key.ExpiresAt) {
+			keys = append(keys, key.JWK())
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
+
+// jwks 公开JWKS，供其他服务验证博客签发的Token
+func jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": currentKeySet().JWKS()})
+}
@@ -0,0 +1,170 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var testUser = &model.User{Model: gorm.Model{ID: 1}, Username: "alice", Email: "alice@example.com", Role: RoleAuthor}
+
+// useKeySet 在测试期间替换全局密钥集合
+func useKeySet(t *testing.T, ks *KeySet) {
+	t.Helper()
+	old := swapKeySet(ks)
+	t.Cleanup(func() { swapKeySet(old) })
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			ks, err := newKeySet(alg, nil)
+			if err != nil {
+				t.Fatalf("newKeySet: %v", err)
+			}
+			useKeySet(t, ks)
+
+			token, err := GenerateToken(testUser, "")
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+			claims, err := ParseToken(token)
+			if err != nil {
+				t.Fatalf("ParseToken: %v", err)
+			}
+			if claims.UserID != 1 {
+				t.Fatalf("user id = %d, want 1", claims.UserID)
+			}
+
+			jwks := ks.JWKS()
+			if len(jwks) != 1 || jwks[0].Alg != alg || jwks[0].Kid != ks.signingKey().ID {
+				t.Fatalf("unexpected jwks: %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ks, err := newKeySet(AlgEdDSA, nil)
+	if err != nil {
+		t.Fatalf("newKeySet: %v", err)
+	}
+	useKeySet(t, ks)
+
+	before, err := GenerateToken(testUser, "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	oldKid := ks.signingKey().ID
+	if err := ks.Rotate(time.Hour); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if ks.signingKey().ID == oldKid {
+		t.Fatal("signing key was not rotated")
+	}
+	if len(ks.JWKS()) != 2 {
+		t.Fatalf("jwks should publish both keys during retention, got %d", len(ks.JWKS()))
+	}
+	if _, err := ParseToken(before); err != nil {
+		t.Fatalf("token signed before rotation should still verify: %v", err)
+	}
+
+	// 保留期结束后旧密钥签发的Token不再被接受
+	ks.keys[oldKid].ExpiresAt = time.Now().Add(-time.Second)
+	if len(ks.JWKS()) != 1 {
+		t.Fatalf("retired key should not be published, got %d keys", len(ks.JWKS()))
+	}
+	if _, err := ParseToken(before); err == nil {
+		t.Fatal("token signed by a retired key should be rejected")
+	}
+}
+
+func TestRejectHMACTokenWithAsymmetricKeys(t *testing.T) {
+	token, err := GenerateToken(testUser, "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	ks, err := newKeySet(AlgRS256, nil)
+	if err != nil {
+		t.Fatalf("newKeySet: %v", err)
+	}
+	useKeySet(t, ks)
+	if _, err := ParseToken(token); err == nil {
+		t.Fatal("HS256 token should be rejected when RS256 is configured")
+	}
+}
+
+func TestKeyDirPersistsRotatedKeys(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := newDirKeySet(AlgEdDSA, dir, time.Hour)
+	if err != nil {
+		t.Fatalf("newDirKeySet: %v", err)
+	}
+	useKeySet(t, ks)
+	before, err := GenerateToken(testUser, "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if err := ks.Rotate(time.Hour); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	after, _ := GenerateToken(testUser, "")
+
+	// 重启后从目录恢复全部密钥，之前签发的Token仍然有效，最新的密钥继续用于签名
+	restarted, err := newDirKeySet(AlgEdDSA, dir, time.Hour)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	useKeySet(t, restarted)
+	if restarted.signingKey().ID != ks.signingKey().ID {
+		t.Fatal("restart did not keep the rotated signing key")
+	}
+	for _, token := range []string{before, after} {
+		if _, err := ParseToken(token); err != nil {
+			t.Fatalf("token invalid after restart: %v", err)
+		}
+	}
+
+	// 保留期结束后轮换时删除旧密钥文件
+	retired := restarted.signingKey().file
+	if err := restarted.Rotate(-time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Rotate(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if slices.Contains(files, retired) || len(files) != 3 {
+		t.Fatalf("key files = %v, want %s removed", files, retired)
+	}
+}
+
+func TestRotateIfDueAdoptsKeysFromOtherInstances(t *testing.T) {
+	dir := t.TempDir()
+	a, err := newDirKeySet(AlgEdDSA, dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newDirKeySet(AlgEdDSA, dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Rotate(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// b的密钥还没到轮换时间，重新读取目录后使用a轮换出的密钥，不再自己生成
+	if err := b.rotateIfDue(24*time.Hour, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if b.signingKey().ID != a.signingKey().ID {
+		t.Fatal("instance did not pick up the key rotated by another instance")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if len(files) != 2 {
+		t.Fatalf("key files = %v", files)
+	}
+}
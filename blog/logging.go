@@ -0,0 +1,248 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 日志格式
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string `yaml:"level"`  // 日志级别：debug、info、warn、error
+	Format string `yaml:"format"` // 输出格式：json、text
+}
+
+// DefaultLogConfig 默认日志配置：info级别的JSON日志
+func DefaultLogConfig() LogConfig {
+	return LogConfig{Level: "info", Format: LogFormatJSON}
+}
+
+// RequestIDHeader 请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// 脱敏后的值
+const redacted = "[REDACTED]"
+
+// 客户端传入的请求ID只接受这些字符，避免日志注入
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// logger blog包使用的日志记录器，通过SetLogger替换
+var logger = slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, nil)})
+
+// NewLogger 按配置创建输出到w的日志记录器
+func NewLogger(cfg LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", cfg.Level)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch cfg.Format {
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case LogFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q", cfg.Format)
+}
+
+// SetLogger 注入blog包使用的日志记录器，日志会自动带上请求ID并对敏感字段脱敏
+func SetLogger(l *slog.Logger) {
+	logger = slog.New(contextHandler{l.Handler()})
+}
+
+// Logger 返回blog包使用的日志记录器
+func Logger() *slog.Logger {
+	return logger
+}
+
+// contextHandler 从context中取出请求ID附加到每条日志上，并对敏感字段脱敏
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	if id := RequestIDFromContext(ctx); id != "" {
+		nr.AddAttrs(slog.String("request_id", id))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, nr)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	res := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		res[i] = redactAttr(a)
+	}
+	return contextHandler{h.Handler.WithAttrs(res)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// isSensitiveKey 判断字段、请求头或查询参数是否包含凭据
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	switch key {
+	case "authorization", "cookie", "set-cookie", "code", "state":
+		return true
+	}
+	return strings.Contains(key, "password") || strings.Contains(key, "secret") || strings.Contains(key, "token")
+}
+
+// redactAttr 把敏感字段的值替换为[REDACTED]，分组中的字段同样处理
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	}
+	return a
+}
+
+// redactQuery 对查询字符串中的敏感参数脱敏
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redacted
+	}
+	for key := range values {
+		if isSensitiveKey(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	return strings.ReplaceAll(values.Encode(), url.QueryEscape(redacted), redacted)
+}
+
+// headerAttrs 把请求头转换成日志字段，敏感的请求头由contextHandler脱敏
+func headerAttrs(h http.Header) slog.Value {
+	attrs := make([]slog.Attr, 0, len(h))
+	for name, values := range h {
+		attrs = append(attrs, slog.String(strings.ToLower(name), strings.Join(values, ", ")))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext 取出context中的请求ID，不在请求中时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// GetRequestID 当前请求的ID
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// RequestID 请求ID中间件：沿用客户端或上游代理传入的X-Request-ID，没有或格式不合法时生成新的ID
+// 请求ID写入响应头，并放入请求的context，供日志使用
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// AccessLog 访问日志中间件，每个请求记录一条日志，5xx记为error，4xx记为warn
+// debug级别下额外记录脱敏后的请求头
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		ctx := c.Request.Context()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("query", redactQuery(c.Request.URL.RawQuery)),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if userID, ok := GetCurrentUserID(c); ok {
+			attrs = append(attrs, slog.Int("user_id", userID))
+		}
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Attr{Key: "headers", Value: headerAttrs(c.Request.Header)})
+		}
+		logger.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Recovery 捕获处理过程中的panic，记录堆栈并返回500
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// 客户端断开时http.Server用这个值中止处理，继续向上抛出
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+			logger.ErrorContext(c.Request.Context(), "panic recovered", "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			c.Abort()
+			if !c.Writer.Written() {
+				writeProblem(c, InternalError("Internal server error", nil))
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,125 @@
+package blog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// captureLogs 把blog包的日志临时输出到内存，返回解析每行JSON日志的函数
+func captureLogs(t *testing.T) func() []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := logger
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { logger = prev })
+	return func() []map[string]any {
+		var lines []map[string]any
+		sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for sc.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+				t.Fatalf("log line is not JSON: %s", sc.Text())
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	r := newTestRouter(t)
+	logs := captureLogs(t)
+	user, token := loginAs(t, "alice", RoleAuthor)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/abc?format=html&token=s3cret", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	if w.Header().Get(RequestIDHeader) != "req-123" || p.RequestID != "req-123" {
+		t.Fatalf("request id not propagated: %s %s", w.Header(), w.Body)
+	}
+
+	var entry map[string]any
+	for _, line := range logs() {
+		if line["msg"] == "request" {
+			entry = line
+		}
+	}
+	if entry == nil {
+		t.Fatal("no access log written")
+	}
+	want := map[string]any{
+		"level":      "WARN",
+		"request_id": "req-123",
+		"method":     "GET",
+		"route":      "/api/posts/:id",
+		"status":     float64(http.StatusBadRequest),
+		"user_id":    float64(user.ID),
+		"query":      "format=html&token=[REDACTED]",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	headers, _ := entry["headers"].(map[string]any)
+	if headers["authorization"] != redacted {
+		t.Errorf("authorization header not redacted: %v", headers)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	r := newTestRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/public/test", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nforged=1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if _, err := uuid.Parse(w.Header().Get(RequestIDHeader)); err != nil {
+		t.Fatalf("expected generated request id, got %q", w.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestLogRedaction(t *testing.T) {
+	logs := captureLogs(t)
+	logger.With("client_secret", "abc").Info("login", "password", "hunter2",
+		slog.Group("body", "email", "a@example.com", "refresh_token", "rt"))
+	line := logs()[0]
+	body, _ := line["body"].(map[string]any)
+	if line["password"] != redacted || line["client_secret"] != redacted || body["refresh_token"] != redacted || body["email"] != "a@example.com" {
+		t.Fatalf("unexpected log line: %v", line)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	r := newTestRouter(t)
+	logs := captureLogs(t)
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	w := doRequest(r, http.MethodGet, "/panic", "", nil)
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	if w.Code != http.StatusInternalServerError || p.Code != "internal_error" || p.RequestID == "" {
+		t.Fatalf("panic response: %d %s", w.Code, w.Body)
+	}
+	recovered := false
+	for _, line := range logs() {
+		if line["msg"] == "panic recovered" && line["panic"] == "boom" && line["request_id"] == p.RequestID {
+			recovered = true
+		}
+	}
+	if !recovered {
+		t.Fatal("panic was not logged with the request id")
+	}
+}
@@ -0,0 +1,173 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 登录失败原因
+const (
+	LoginReasonInvalidCredentials  = "invalid_credentials"
+	LoginReasonLocked              = "locked"
+	LoginReasonUnverified          = "email_unverified"
+	LoginReasonInvalidSecondFactor = "invalid_2fa"
+)
+
+// 计入失败次数的原因
+var countedLoginFailures = []string{LoginReasonInvalidCredentials, LoginReasonInvalidSecondFactor}
+
+// 登录相关错误，邮箱不存在和密码错误都返回ErrInvalidCredentials，避免泄露账号是否存在
+var (
+	ErrInvalidCredentials = UnauthorizedError("invalid_credentials", "Invalid email or password")
+	ErrLoginLocked        = NewError(KindRateLimited, "login_locked", "Too many failed login attempts, please try again later")
+	ErrEmailNotVerified   = ForbiddenError("email_not_verified", "Email not verified")
+)
+
+// LoginPolicy 登录失败的惩罚策略
+// 窗口内失败次数超过FreeAttempts后，每次失败需要等待的时间从1秒开始翻倍；达到MaxFailures后锁定Lockout
+type LoginPolicy struct {
+	FreeAttempts   int
+	MaxFailures    int
+	Window         time.Duration // 只统计这段时间内的失败
+	Lockout        time.Duration
+	ResetOnSuccess bool // 登录成功后是否清零失败次数
+}
+
+// 按账号（邮箱）和按IP的登录策略
+// 同一IP下可能有很多用户，额度更宽松，并且成功登录不会清零，避免攻击者用自己的账号重置计数
+var (
+	AccountLoginPolicy = LoginPolicy{FreeAttempts: 3, MaxFailures: 10, Window: 15 * time.Minute, Lockout: 15 * time.Minute, ResetOnSuccess: true}
+	IPLoginPolicy      = LoginPolicy{FreeAttempts: 10, MaxFailures: 50, Window: 15 * time.Minute, Lockout: 15 * time.Minute}
+)
+
+// LoginHistoryRetention 关联到用户的登录记录的保留时间，用户可以在登录记录中查看
+var LoginHistoryRetention = 30 * 24 * time.Hour
+
+// PurgeLoginAttempts 清理不再需要的登录记录
+// 未关联用户的记录（邮箱不存在）只用于统计失败次数，超过统计窗口后删除；关联用户的记录保留LoginHistoryRetention
+func PurgeLoginAttempts(now time.Time) error {
+	window := max(AccountLoginPolicy.Window, IPLoginPolicy.Window)
+	if err := GetDB().Where("user_id IS NULL AND created_at < ?", now.Add(-window)).Delete(&model.LoginAttempt{}).Error; err != nil {
+		return err
+	}
+	return GetDB().Where("created_at < ?", now.Add(-max(LoginHistoryRetention, window))).Delete(&model.LoginAttempt{}).Error
+}
+
+// delay 失败failures次之后，距离最后一次失败需要等待的时间
+func (p LoginPolicy) delay(failures int) time.Duration {
+	if failures < p.FreeAttempts {
+		return 0
+	}
+	if failures >= p.MaxFailures {
+		return p.Lockout
+	}
+	return min(time.Second<<(failures-p.FreeAttempts), p.Lockout)
+}
+
+// loginWait 按列（email或ip）统计窗口内的失败次数，返回还需要等待的时间
+func loginWait(column, value string, policy LoginPolicy, now time.Time) (time.Duration, error) {
+	since := now.Add(-policy.Window)
+	if policy.ResetOnSuccess {
+		var last model.LoginAttempt
+		err := GetDB().Where(column+" = ? AND success = ?", value, true).Order("created_at DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return 0, err
+		}
+		if last.ID != 0 && last.CreatedAt.After(since) {
+			since = last.CreatedAt
+		}
+	}
+
+	failures := func() *gorm.DB {
+		return GetDB().Model(&model.LoginAttempt{}).
+			Where(column+" = ? AND reason IN ? AND created_at > ?", value, countedLoginFailures, since)
+	}
+	var count int64
+	if err := failures().Count(&count).Error; err != nil {
+		return 0, err
+	}
+	wait := policy.delay(int(count))
+	if wait == 0 {
+		return 0, nil
+	}
+	var latest model.LoginAttempt
+	if err := failures().Order("created_at DESC").Limit(1).Find(&latest).Error; err != nil {
+		return 0, err
+	}
+	return max(0, wait-now.Sub(latest.CreatedAt)), nil
+}
+
+// loginBlockedFor 返回账号和IP中较长的等待时间，不区分账号是否存在
+func loginBlockedFor(email, ip string, now time.Time) (time.Duration, error) {
+	byAccount, err := loginWait("email", email, AccountLoginPolicy, now)
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := loginWait("ip", ip, IPLoginPolicy, now)
+	if err != nil {
+		return 0, err
+	}
+	return max(byAccount, byIP), nil
+}
+
+// recordLoginAttempt 保存一条登录记录，失败时只打印日志，不影响登录结果
+func recordLoginAttempt(c *gin.Context, userID *uint, email string, success bool, reason string) {
+	ua := []rune(c.Request.UserAgent())
+	attempt := model.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		IP:        c.ClientIP(),
+		UserAgent: string(ua[:min(len(ua), 255)]),
+		Success:   success,
+		Reason:    reason,
+	}
+	if err := GetDB().Create(&attempt).Error; err != nil {
+		logger.ErrorContext(c.Request.Context(), "Failed to record login attempt", "error", err)
+	}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyPassword 邮箱不存在时也做一次bcrypt比较，使响应时间与密码错误时一致
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// abortLoginLocked 登录被暂时锁定时返回429
+func abortLoginLocked(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+	respondError(c, ErrLoginLocked)
+}
+
+// normalizeEmail 登录计数使用的邮箱形式
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginSessions GET /api/profile/sessions
+// 当前用户的登录历史，包括针对该账号的失败尝试
+func loginSessions(c *gin.Context) {
+	userID, _ := GetCurrentUserID(c)
+	q := GetDB().Model(&model.LoginAttempt{}).Where("user_id = ?", userID)
+	res, err := paginate(c, q, []string{"created_at", "id"}, "-created_at", func(a *model.LoginAttempt) (time.Time, uint) {
+		return a.CreatedAt, a.ID
+	})
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	respondPage(c, res.Items, res.Pagination, res.Links)
+}
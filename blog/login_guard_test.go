@@ -0,0 +1,130 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginPolicyDelay(t *testing.T) {
+	p := LoginPolicy{FreeAttempts: 3, MaxFailures: 10, Lockout: 15 * time.Minute}
+	cases := map[int]time.Duration{0: 0, 2: 0, 3: time.Second, 4: 2 * time.Second, 9: 64 * time.Second, 10: 15 * time.Minute}
+	for failures, want := range cases {
+		if got := p.delay(failures); got != want {
+			t.Errorf("delay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	r := newTestRouter(t)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	now := time.Now()
+	user := model.User{Username: "alice", Email: "alice@example.com", Password: string(hash), EmailVerifiedAt: &now}
+	GetDB().Create(&user)
+	login := func(email, password string) (int, string) {
+		w := doRequest(r, http.MethodPost, "/auth/login", "", gin.H{"email": email, "password": password})
+		var res Problem
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res.Code + ": " + res.Detail
+	}
+
+	// 邮箱不存在和密码错误的响应相同
+	code, unknown := login("nobody@example.com", "secret1")
+	if code != http.StatusUnauthorized {
+		t.Fatalf("unknown email: %d", code)
+	}
+	for range AccountLoginPolicy.FreeAttempts {
+		code, msg := login("alice@example.com", "wrong")
+		if code != http.StatusUnauthorized || msg != unknown {
+			t.Fatalf("wrong password: %d %q, want %q", code, msg, unknown)
+		}
+	}
+	// 失败次数用完后即使密码正确也要等待
+	if code, _ := login("Alice@example.com", "secret1"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected lockout, got %d", code)
+	}
+
+	GetDB().Model(&model.LoginAttempt{}).Where("1 = 1").Update("created_at", time.Now().Add(-2*time.Second))
+	if code, _ := login("alice@example.com", "secret1"); code != http.StatusOK {
+		t.Fatalf("login after delay: %d", code)
+	}
+	// 成功登录后账号的失败次数清零
+	if code, _ := login("alice@example.com", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("failures should reset after success, got %d", code)
+	}
+
+	pair, _ := IssueTokenPair(&user)
+	w := doRequest(r, http.MethodGet, "/api/profile/sessions", pair.AccessToken, nil)
+	var res struct{ Data []model.LoginAttempt }
+	json.Unmarshal(w.Body.Bytes(), &res)
+	// 3次失败、1次成功、1次失败；锁定期间的尝试不验证账号，不关联用户
+	if w.Code != http.StatusOK || len(res.Data) != 5 {
+		t.Fatalf("sessions: %d %s", w.Code, w.Body)
+	}
+	if !res.Data[1].Success || res.Data[0].Reason != LoginReasonInvalidCredentials {
+		t.Fatalf("unexpected history order: %+v", res.Data)
+	}
+}
+
+func TestLoginIPIgnoresForwardedFor(t *testing.T) {
+	r := newTestRouter(t)
+	login := func(email, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"`+email+`","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 登录记录使用连接的对端地址，而不是客户端传入的X-Forwarded-For
+	login("a@example.com", "198.51.100.1")
+	var attempt model.LoginAttempt
+	GetDB().Last(&attempt)
+	if attempt.IP != "192.0.2.1" {
+		t.Fatalf("recorded ip = %q, want remote address", attempt.IP)
+	}
+
+	// 同一IP的失败次数用完后，换X-Forwarded-For也无法绕过锁定
+	for i := range IPLoginPolicy.MaxFailures {
+		GetDB().Create(&model.LoginAttempt{Email: fmt.Sprintf("u%d@example.com", i), IP: "192.0.2.1", Reason: LoginReasonInvalidCredentials})
+	}
+	if w := login("b@example.com", "198.51.100.2"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For bypassed the IP lockout: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestPurgeLoginAttempts(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "alice")
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	for _, attempt := range []model.LoginAttempt{
+		{Email: "ghost@example.com", IP: "192.0.2.1", Reason: LoginReasonInvalidCredentials, CreatedAt: ago(time.Hour)},
+		{Email: "ghost@example.com", IP: "192.0.2.1", Reason: LoginReasonInvalidCredentials, CreatedAt: ago(time.Minute)},
+		{UserID: &user.ID, Email: user.Email, IP: "192.0.2.1", Success: true, CreatedAt: ago(24 * time.Hour)},
+		{UserID: &user.ID, Email: user.Email, IP: "192.0.2.1", Success: true, CreatedAt: ago(LoginHistoryRetention + time.Hour)},
+	} {
+		GetDB().Create(&attempt)
+	}
+
+	// 统计窗口内的失败记录和保留期内的用户登录记录不受影响
+	if err := PurgeLoginAttempts(now); err != nil {
+		t.Fatalf("PurgeLoginAttempts: %v", err)
+	}
+	var ids []uint
+	GetDB().Model(&model.LoginAttempt{}).Order("id").Pluck("id", &ids)
+	if fmt.Sprint(ids) != "[2 3]" {
+		t.Fatalf("remaining attempts = %v, want [2 3]", ids)
+	}
+}
@@ -0,0 +1,158 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 支持的邮件驱动
+const (
+	MailerSMTP   = "smtp"
+	MailerMemory = "memory"
+)
+
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver string     `yaml:"driver"`  // smtp；memory只保存在内存中，用于测试和本地开发
+	From   string     `yaml:"from"`    // 发件人地址
+	AppURL string     `yaml:"app_url"` // 邮件中链接的前缀，例如 https://blog.example.com
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig SMTP服务器配置，服务器支持时使用STARTTLS
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Mail 一封纯文本邮件
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+// 全局邮件发送器和链接前缀，由InitMailer初始化
+var (
+	mailer Mailer = &MemoryMailer{}
+	AppURL        = "http://localhost:8080"
+)
+
+// MailSendTimeout 后台发送一封邮件的超时时间
+var MailSendTimeout = 30 * time.Second
+
+// pendingMail 正在后台发送的邮件
+var pendingMail sync.WaitGroup
+
+// sendInBackground 在后台执行send发送邮件，调用方立即返回，接口的响应时间不会因为是否发送邮件而不同
+// 请求结束后发送不会被取消，失败时以errMsg记录日志
+func sendInBackground(ctx context.Context, errMsg string, userID uint, send func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	pendingMail.Go(func() {
+		ctx, cancel := context.WithTimeout(ctx, MailSendTimeout)
+		defer cancel()
+		if err := send(ctx); err != nil {
+			logger.ErrorContext(ctx, errMsg, "user_id", userID, "error", err)
+		}
+	})
+}
+
+// WaitForMail 等待后台发送的邮件全部完成
+func WaitForMail() {
+	pendingMail.Wait()
+}
+
+// InitMailer 根据配置初始化邮件发送器
+func InitMailer(cfg MailConfig) error {
+	switch cfg.Driver {
+	case MailerSMTP:
+		if cfg.SMTP.Host == "" || cfg.From == "" {
+			return errors.New("smtp mailer requires host and from")
+		}
+		mailer = &SMTPMailer{Config: cfg.SMTP, From: cfg.From}
+	case MailerMemory:
+		logger.Warn("Using in-memory mailer, emails will not be delivered")
+		mailer = &MemoryMailer{}
+	default:
+		return fmt.Errorf("unsupported mail driver %q", cfg.Driver)
+	}
+	AppURL = strings.TrimSuffix(cfg.AppURL, "/")
+	return nil
+}
+
+// SMTPMailer 通过SMTP发送邮件
+type SMTPMailer struct {
+	Config SMTPConfig
+	From   string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail Mail) error {
+	addr := net.JoinHostPort(m.Config.Host, strconv.Itoa(m.Config.Port))
+	var auth smtp.Auth
+	if m.Config.Username != "" {
+		auth = smtp.PlainAuth("", m.Config.Username, m.Config.Password, m.Config.Host)
+	}
+	// net/smtp不支持context，发送在单独的goroutine中进行，超时后直接返回
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, m.From, []string{mail.To}, buildMessage(m.From, mail))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMessage 组装邮件头和正文，主题按RFC 2047编码以支持中文
+func buildMessage(from string, mail Mail) []byte {
+	// 防止头部注入
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	b.WriteString("From: " + clean.Replace(from) + "\r\n")
+	b.WriteString("To: " + clean.Replace(mail.To) + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", clean.Replace(mail.Subject)) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// MemoryMailer 把邮件保存在内存中，用于测试和本地开发
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Mail
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, mail Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, mail)
+	return nil
+}
+
+// Sent 等待后台发送完成后返回已发送的邮件
+func (m *MemoryMailer) Sent() []Mail {
+	WaitForMail()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Mail(nil), m.sent...)
+}
@@ -0,0 +1,73 @@
+package blog
+
+import (
+	"bytes"
+	"html"
+	model "job/blog/Model"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// 文章内容的返回格式
+const (
+	ContentFormatMarkdown = "markdown"
+	ContentFormatHTML     = "html"
+)
+
+// markdown 渲染器，支持GFM（表格、删除线、任务列表、自动链接）
+// 允许内嵌HTML，由sanitizer统一过滤
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+)
+
+// sanitizer 在UGC白名单的基础上保留代码块的语言class和任务列表的复选框
+// 链接只允许http、https、mailto和相对地址，并自动加上rel="nofollow noopener"
+var sanitizer = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// RenderMarkdown 把Markdown渲染为经过白名单过滤的HTML
+func RenderMarkdown(src string) string {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		// 写入内存缓冲区不会失败，这里只是兜底，退化为转义后的原文
+		return "<pre>" + html.EscapeString(src) + "</pre>"
+	}
+	return sanitizer.Sanitize(buf.String())
+}
+
+// contentFormat 解析 format 查询参数，默认返回Markdown原文
+func contentFormat(c *gin.Context) (string, error) {
+	format := c.DefaultQuery("format", ContentFormatMarkdown)
+	if format != ContentFormatMarkdown && format != ContentFormatHTML {
+		return "", invalidQuery("invalid_query.format")
+	}
+	return format, nil
+}
+
+// formatPosts 按返回格式处理文章内容，html格式时Content替换为渲染后的HTML
+func formatPosts(format string, posts []model.Post) {
+	if format != ContentFormatHTML {
+		return
+	}
+	for i := range posts {
+		if posts[i].ContentHTML == "" {
+			// 渲染字段出现之前保存的文章
+			posts[i].ContentHTML = RenderMarkdown(posts[i].Content)
+		}
+		posts[i].Content = posts[i].ContentHTML
+	}
+}
@@ -0,0 +1,63 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	src := "# Title\n\n<script>alert(1)</script>\n\n" +
+		"[bad](javascript:alert(1)) [good](https://example.com) <img src=x onerror=alert(1)>\n\n" +
+		"```go\nfmt.Println(\"<hi>\")\n```\n\n- [x] done\n"
+	got := RenderMarkdown(src)
+
+	for _, bad := range []string{"<script", "javascript:", "onerror"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("output contains %q:\n%s", bad, got)
+		}
+	}
+	for _, want := range []string{
+		"<h1>Title</h1>",
+		`<a href="https://example.com" rel="nofollow noopener" target="_blank">good</a>`,
+		`<code class="language-go">fmt.Println(&#34;&lt;hi&gt;&#34;)`,
+		`<input checked="" disabled="" type="checkbox"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPostContentFormat(t *testing.T) {
+	r := newTestRouter(t)
+	_, token := loginAs(t, "author", RoleAuthor)
+
+	w := doRequest(r, http.MethodPost, "/api/posts", token, gin.H{"title": "t", "content": "**bold** <b onclick=x>hi</b>"})
+	var post model.Post
+	decodeData(w, &post)
+	path := fmt.Sprintf("/api/posts/%d", post.ID)
+
+	get := func(query string) string {
+		w := doRequest(r, http.MethodGet, path+query, token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d: %s", query, w.Code, w.Body)
+		}
+		var p model.Post
+		decodeData(w, &p)
+		return p.Content
+	}
+	if got := get(""); got != "**bold** <b onclick=x>hi</b>" {
+		t.Fatalf("markdown = %q", got)
+	}
+	if got := get("?format=html"); got != "<p><strong>bold</strong> <b>hi</b></p>\n" {
+		t.Fatalf("html = %q", got)
+	}
+	if w := doRequest(r, http.MethodGet, path+"?format=pdf", token, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid format status = %d, want 400", w.Code)
+	}
+}
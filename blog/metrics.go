@@ -0,0 +1,185 @@
+package blog
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// MetricsPath 暴露Prometheus指标的路由
+const MetricsPath = "/metrics"
+
+// MetricsConfig 指标配置，指标只在单独的监听地址上暴露，不经过对外的HTTP服务
+type MetricsConfig struct {
+	Addr  string `yaml:"addr"`  // 指标服务的监听地址，为空表示不启用
+	Token string `yaml:"token"` // 抓取指标时需要的Bearer Token，为空表示不认证
+}
+
+// 认证失败的原因，作为blog_auth_failures_total的reason标签
+const (
+	AuthFailureMissingHeader = "missing_header"
+	AuthFailureMissingToken  = "missing_token"
+	AuthFailureExpired       = "expired"
+	AuthFailureBadSignature  = "bad_signature"
+	AuthFailureRevoked       = "revoked"
+	AuthFailurePersonalToken = "invalid_personal_token"
+	AuthFailureInvalid       = "invalid"
+)
+
+// metricsRegistry blog包的指标注册表，除业务指标外还包含Go运行时和进程指标
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_http_requests_total",
+		Help: "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blog_http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	authFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_auth_failures_total",
+		Help: "Number of rejected authentications by reason.",
+	}, []string{"reason"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blog_db_query_duration_seconds",
+		Help:    "Database query latency by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+)
+
+// dbStatsCollector 当前数据库连接池的指标，重新连接数据库时替换
+var dbStatsCollector prometheus.Collector
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal, httpRequestDuration, authFailuresTotal, dbQueryDuration,
+	)
+}
+
+// MetricsRegistry 返回blog包的指标注册表，可以注册其他指标
+func MetricsRegistry() *prometheus.Registry {
+	return metricsRegistry
+}
+
+// Metrics 请求指标中间件，按路由模板统计请求数和耗时，未匹配的路由统一记为unmatched，避免标签无限增长
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler 指标服务的处理器，GET /metrics返回Prometheus文本格式的指标
+// token不为空时要求请求带有Authorization: Bearer <token>
+func MetricsHandler(token string) http.Handler {
+	metrics := promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+	mux := http.NewServeMux()
+	mux.HandleFunc(MetricsPath, func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+	return mux
+}
+
+// recordAuthFailure 记录一次认证失败
+func recordAuthFailure(reason string) {
+	authFailuresTotal.WithLabelValues(reason).Inc()
+}
+
+// tokenFailureReason 把JWT解析错误归类为认证失败的原因
+func tokenFailureReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return AuthFailureExpired
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return AuthFailureBadSignature
+	}
+	return AuthFailureInvalid
+}
+
+// setDBStatsCollector 注册数据库连接池指标，替换之前连接的指标
+func setDBStatsCollector(sqlDB *sql.DB, dbName string) error {
+	if dbStatsCollector != nil {
+		metricsRegistry.Unregister(dbStatsCollector)
+	}
+	dbStatsCollector = collectors.NewDBStatsCollector(sqlDB, dbName)
+	return metricsRegistry.Register(dbStatsCollector)
+}
+
+// MetricsPlugin 统计每条SQL耗时的GORM插件
+type MetricsPlugin struct{}
+
+// 插件在Statement上保存开始时间使用的键
+const queryStartKey = "metrics:start"
+
+// Name 插件名称
+func (MetricsPlugin) Name() string {
+	return "blog:metrics"
+}
+
+// Initialize 在各类操作的前后注册回调
+func (p MetricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", p.before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", p.before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", p.before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", p.before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", p.after("raw")),
+	)
+}
+
+func (MetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (MetricsPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		dbQueryDuration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(start).Seconds())
+	}
+}
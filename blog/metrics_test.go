@@ -0,0 +1,93 @@
+package blog
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	r := newTestRouter(t)
+	_, token := loginAs(t, "reader", RoleReader)
+	ok := httpRequestsTotal.WithLabelValues(http.MethodGet, "/api/posts/:id", "404")
+	unmatched := httpRequestsTotal.WithLabelValues(http.MethodGet, "unmatched", "404")
+	before, beforeUnmatched := testutil.ToFloat64(ok), testutil.ToFloat64(unmatched)
+
+	doRequest(r, http.MethodGet, "/api/posts/999", token, nil)
+	doRequest(r, http.MethodGet, "/no/such/route", "", nil)
+	if got := testutil.ToFloat64(ok) - before; got != 1 {
+		t.Fatalf("requests for /api/posts/:id = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(unmatched) - beforeUnmatched; got != 1 {
+		t.Fatalf("unmatched requests = %v, want 1", got)
+	}
+
+	// 对外的路由上没有指标，指标服务需要Token
+	if w := doRequest(r, http.MethodGet, MetricsPath, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("metrics exposed on the public router: %d", w.Code)
+	}
+	metrics := MetricsHandler("scrape-secret")
+	if w := doRequest(metrics, http.MethodGet, MetricsPath, "wrong", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("metrics without token: %d", w.Code)
+	}
+	w := doRequest(metrics, http.MethodGet, MetricsPath, "scrape-secret", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("metrics endpoint: %d %s", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`blog_http_request_duration_seconds_bucket{method="GET",route="/api/posts/:id",status="404"`,
+		`blog_db_query_duration_seconds_count{operation="query",table="posts"}`,
+		`blog_db_query_duration_seconds_count{operation="create",table="users"}`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
+
+func TestAuthFailureMetrics(t *testing.T) {
+	r := newTestRouter(t)
+	user, token := loginAs(t, "reader", RoleReader)
+
+	// 用另一个Token的签名替换，签名与内容不匹配
+	other, err := GenerateToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + parts[1] + "." + strings.Split(other, ".")[2]
+
+	prev := TokenExpireDuration
+	TokenExpireDuration = -time.Minute
+	expired, err := GenerateToken(user, "")
+	TokenExpireDuration = prev
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		reason string
+		token  string
+	}{
+		{AuthFailureMissingHeader, ""},
+		{AuthFailureBadSignature, forged},
+		{AuthFailureExpired, expired},
+		{AuthFailureInvalid, "not-a-jwt"},
+		{AuthFailurePersonalToken, PATPrefix + "unknown"},
+	}
+	for _, tc := range cases {
+		counter := authFailuresTotal.WithLabelValues(tc.reason)
+		before := testutil.ToFloat64(counter)
+		if w := doRequest(r, http.MethodGet, "/api/profile", tc.token, nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status = %d", tc.reason, w.Code)
+		}
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Errorf("%s: counter increased by %v, want 1", tc.reason, got)
+		}
+	}
+}
@@ -1,22 +1,28 @@
 package blog
 
 import (
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware JWT认证中间件
+// 认证相关错误
+var (
+	ErrMissingAuthHeader = UnauthorizedError("missing_authorization", "Authorization header is required")
+	ErrMissingToken      = UnauthorizedError("missing_token", "Token is required")
+	ErrInvalidToken      = UnauthorizedError("invalid_token", "Invalid or expired token")
+	ErrTokenRevoked      = UnauthorizedError("token_revoked", "Token has been revoked")
+	ErrMFARequired       = ForbiddenError("mfa_required", "Two-factor authentication required")
+)
+
+// AuthMiddleware 认证中间件，接受登录签发的JWT或个人访问Token
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从Authorization header获取token
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header is required",
-			})
-			c.Abort()
+			recordAuthFailure(AuthFailureMissingHeader)
+			respondError(c, ErrMissingAuthHeader)
 			return
 		}
 
@@ -29,34 +35,63 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Token is required",
-			})
-			c.Abort()
+			recordAuthFailure(AuthFailureMissingToken)
+			respondError(c, ErrMissingToken)
+			return
+		}
+
+		// 个人访问Token，权限和授权范围同时生效
+		if IsPersonalAccessToken(tokenString) {
+			claims, scopes, err := AuthenticatePAT(tokenString)
+			if err != nil {
+				recordAuthFailure(AuthFailurePersonalToken)
+				respondError(c, err)
+				return
+			}
+			setCurrentUser(c, claims, false)
+			c.Set("scopes", scopes)
+			c.Next()
 			return
 		}
 
 		// 解析Token
 		claims, err := ParseToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid token: " + err.Error(),
-			})
-			c.Abort()
+			recordAuthFailure(tokenFailureReason(err))
+			respondError(c, ErrInvalidToken.WithCause(err))
+			return
+		}
+
+		// 检查Token是否已被吊销（登出或刷新Token被重放）
+		revoked, err := IsTokenRevoked(claims)
+		if err != nil {
+			respondError(c, InternalError("Failed to check token revocation", err))
+			return
+		}
+		if revoked {
+			recordAuthFailure(AuthFailureRevoked)
+			respondError(c, ErrTokenRevoked)
 			return
 		}
 
 		// 将用户信息存储到上下文中，供后续处理函数使用
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("email", claims.Email)
-		c.Set("claims", claims)
+		setCurrentUser(c, claims, claims.MFA)
 
 		c.Next()
 	}
 }
 
-// OptionalAuthMiddleware 可选的JWT认证中间件（不强制要求token）
+// setCurrentUser 将认证后的用户信息存储到上下文中
+func setCurrentUser(c *gin.Context, claims *CustomClaims, mfa bool) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("mfa", mfa)
+	c.Set("claims", claims)
+}
+
+// OptionalAuthMiddleware 可选的认证中间件（不强制要求token），同样接受个人访问Token
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -77,17 +112,49 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if IsPersonalAccessToken(tokenString) {
+			if claims, scopes, err := AuthenticatePAT(tokenString); err == nil {
+				setCurrentUser(c, claims, false)
+				c.Set("scopes", scopes)
+				c.Set("authenticated", true)
+			}
+			c.Next()
+			return
+		}
+
 		claims, err := ParseToken(tokenString)
 		if err == nil {
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("email", claims.Email)
-			c.Set("claims", claims)
+			if revoked, err := IsTokenRevoked(claims); err != nil || revoked {
+				c.Next()
+				return
+			}
+			setCurrentUser(c, claims, claims.MFA)
 			c.Set("authenticated", true)
 		}
 	}
 }
 
+// RequireMFA 要求当前会话完成了两步验证，用于敏感操作
+// 启用两步验证之前登录的会话也不满足要求
+func RequireMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if mfa, _ := c.Get("mfa"); mfa != true {
+			respondError(c, ErrMFARequired)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Deprecated 标记已废弃的路由，通过响应头提示客户端迁移到新路由
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
+
 // GetCurrentUser 从上下文中获取当前用户信息
 func GetCurrentUser(c *gin.Context) (*CustomClaims, bool) {
 	if claims, exists := c.Get("claims"); exists {
@@ -101,8 +168,11 @@ func GetCurrentUser(c *gin.Context) (*CustomClaims, bool) {
 // GetCurrentUserID 从上下文中获取当前用户ID
 func GetCurrentUserID(c *gin.Context) (int, bool) {
 	if userID, exists := c.Get("user_id"); exists {
-		if id, ok := userID.(int); ok {
+		switch id := userID.(type) {
+		case int:
 			return id, true
+		case uint:
+			return int(id), true
 		}
 	}
 	return 0, false
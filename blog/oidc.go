@@ -0,0 +1,608 @@
+package blog
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	model "job/blog/Model"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// OIDCConfig 第三方登录配置
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig `yaml:"providers"`
+}
+
+// OIDCProviderConfig OpenID Connect身份提供方配置
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name"`          // 路由中使用的名称，例如 /auth/oidc/google
+	Issuer       string   `yaml:"issuer"`        // 发现文档位于 {issuer}/.well-known/openid-configuration
+	ClientID     string   `yaml:"client_id"`     // 在提供方注册的客户端ID
+	ClientSecret string   `yaml:"client_secret"` // 公开客户端可以留空，只依靠PKCE
+	RedirectURL  string   `yaml:"redirect_url"`  // 默认 {mail.app_url}/auth/oidc/{name}/callback
+	Scopes       []string `yaml:"scopes"`        // 默认 openid email profile
+}
+
+const (
+	oidcStateCookie  = "oidc_state"
+	purposeOIDCState = "oidc-state"
+)
+
+// OIDC登录相关的时间限制
+var (
+	OIDCStateTTL        = 10 * time.Minute // 从跳转到提供方到回调的最长时间
+	oidcKeyRefreshDelay = time.Minute      // 遇到未知kid时重新拉取JWKS的最小间隔
+)
+
+// 提供方签发ID Token允许使用的算法
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var (
+	ErrUnknownOIDCProvider   = NotFoundError("unknown_provider", "Unknown identity provider")
+	ErrInvalidOIDCState      = ValidationError("invalid_login_state", "Invalid or expired login state")
+	ErrOIDCProviderError     = ValidationError("provider_error", "Identity provider returned an error")
+	ErrOIDCUnavailable       = NewError(KindUnavailable, "provider_unavailable", "Identity provider unavailable")
+	ErrOIDCExchange          = UnauthorizedError("code_exchange_failed", "Failed to exchange authorization code")
+	ErrInvalidIDToken        = UnauthorizedError("invalid_id_token", "Invalid id token")
+	ErrOIDCEmailUnverified   = ForbiddenError("email_unverified", "Identity provider did not return a verified email")
+	ErrOIDCAccountUnverified = ConflictError("account_unverified", "An unverified account uses this email, verify the email or reset the password before signing in with the identity provider")
+)
+
+// oidcDiscovery 发现文档中用到的字段
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider 一个身份提供方，发现文档和公钥在第一次使用时拉取并缓存
+type OIDCProvider struct {
+	cfg    OIDCProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+	keysAt    time.Time
+}
+
+// 全局身份提供方，由InitOIDC初始化，键为提供方名称
+var oidcProviders = map[string]*OIDCProvider{}
+
+// InitOIDC 根据配置注册身份提供方，需要在InitMailer之后调用以便使用AppURL生成默认回调地址
+func InitOIDC(cfg OIDCConfig) {
+	providers := map[string]*OIDCProvider{}
+	for _, p := range cfg.Providers {
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		} else if !slices.Contains(p.Scopes, "openid") {
+			p.Scopes = append([]string{"openid"}, p.Scopes...)
+		}
+		if p.RedirectURL == "" {
+			p.RedirectURL = AppURL + "/auth/oidc/" + url.PathEscape(p.Name) + "/callback"
+		}
+		providers[p.Name] = NewOIDCProvider(p)
+	}
+	oidcProviders = providers
+}
+
+// NewOIDCProvider 创建身份提供方，不会立即访问网络
+func NewOIDCProvider(cfg OIDCProviderConfig) *OIDCProvider {
+	return &OIDCProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// getJSON 请求提供方的JSON接口
+func (p *OIDCProvider) getJSON(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// discover 拉取并缓存发现文档，文档中的issuer必须与配置完全一致
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc oidcDiscovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if doc.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing authorization_endpoint, token_endpoint or jwks_uri")
+	}
+	p.discovery = &doc
+	return &doc, nil
+}
+
+// publicKey 按kid查找提供方的公钥，找不到时重新拉取JWKS以支持提供方轮换密钥
+func (p *OIDCProvider) publicKey(ctx context.Context, doc *oidcDiscovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if p.keys != nil && time.Since(p.keysAt) < oidcKeyRefreshDelay {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := parseJWK(jwk); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys, p.keysAt = keys, time.Now()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// parseJWK 把JWK转换成公钥，支持RSA、EC（P-256/384/521）和Ed25519
+func parseJWK(jwk JWK) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, err1 := decode(jwk.N)
+		e, err2 := decode(jwk.E)
+		if err := errors.Join(err1, err2); err != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err1 := decode(jwk.X)
+		y, err2 := decode(jwk.Y)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, errors.New("invalid EC key")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return key, nil
+	case "OKP":
+		x, err := decode(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid OKP key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+// authCodeURL 生成跳转到提供方的授权地址，使用PKCE（S256）
+func (p *OIDCProvider) authCodeURL(doc *oidcDiscovery, state, nonce, verifier string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// exchange 用授权码换取ID Token，有client_secret时使用client_secret_basic认证
+func (p *OIDCProvider) exchange(ctx context.Context, doc *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("token endpoint: status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("token endpoint: status %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token endpoint: response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// flexibleBool 兼容部分提供方把email_verified写成字符串"true"
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	*b = flexibleBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// idTokenClaims ID Token中用到的Claims
+type idTokenClaims struct {
+	Nonce             string       `json:"nonce"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	PreferredUsername string       `json:"preferred_username"`
+	AuthorizedParty   string       `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// verifyIDToken 校验ID Token的签名、签发者、受众、有效期和nonce
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, doc *oidcDiscovery, raw, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, doc, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Subject == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: subject or nonce mismatch", ErrInvalidIDToken)
+	}
+	// 有多个受众时azp必须是本客户端
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// oidcStateClaims 保存在Cookie中的登录状态，由本服务签名，回调时与查询参数比对
+type oidcStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE的code_verifier
+	jwt.RegisteredClaims
+}
+
+// randomString 生成n字节的随机字符串（base64url编码）
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// oidcProvider 按路由参数查找身份提供方，找不到时返回404
+func oidcProvider(c *gin.Context) (*OIDCProvider, bool) {
+	p, ok := oidcProviders[c.Param("provider")]
+	if !ok {
+		respondError(c, ErrUnknownOIDCProvider)
+	}
+	return p, ok
+}
+
+// setOIDCStateCookie 写入或清除登录状态Cookie，maxAge小于0时清除
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, "/auth/oidc", "", strings.HasPrefix(AppURL, "https://"), true)
+}
+
+// listOIDCProviders GET /auth/oidc
+func listOIDCProviders(c *gin.Context) {
+	names := make([]string, 0, len(oidcProviders))
+	for name := range oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	respondData(c, http.StatusOK, names)
+}
+
+// oidcLogin GET /auth/oidc/:provider
+// 生成state、nonce和PKCE参数，签名后放入Cookie，然后跳转到提供方的授权页面
+func oidcLogin(c *gin.Context) {
+	p, ok := oidcProvider(c)
+	if !ok {
+		return
+	}
+	doc, err := p.discover(c.Request.Context())
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "OIDC discovery failed", "provider", p.cfg.Name, "error", err)
+		respondError(c, ErrOIDCUnavailable)
+		return
+	}
+
+	state, err1 := randomString(16)
+	nonce, err2 := randomString(16)
+	verifier, err3 := randomString(32)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		respondError(c, InternalError("Failed to start login", err))
+		return
+	}
+	now := time.Now()
+	cookie, err := signClaims(oidcStateClaims{
+		Provider: p.cfg.Name,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(OIDCStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "jss",
+			Subject:   purposeOIDCState,
+		},
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to start login", err))
+		return
+	}
+
+	setOIDCStateCookie(c, cookie, int(OIDCStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, p.authCodeURL(doc, state, nonce, verifier))
+}
+
+// oidcCallback GET /auth/oidc/:provider/callback
+// 校验state，用授权码换取并校验ID Token，关联或创建用户后签发博客自己的Token
+func oidcCallback(c *gin.Context) {
+	p, ok := oidcProvider(c)
+	if !ok {
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		respondError(c, ErrOIDCProviderError.WithDetail("provider_error.detail", e))
+		return
+	}
+
+	raw, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	state := &oidcStateClaims{}
+	_, err := jwt.ParseWithClaims(raw, state, keySetKeyFunc, jwt.WithSubject(purposeOIDCState), jwt.WithExpirationRequired())
+	if err != nil || state.Provider != p.cfg.Name || state.State == "" || state.State != c.Query("state") || c.Query("code") == "" {
+		respondError(c, ErrInvalidOIDCState)
+		return
+	}
+
+	ctx := c.Request.Context()
+	doc, err := p.discover(ctx)
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "OIDC discovery failed", "provider", p.cfg.Name, "error", err)
+		respondError(c, ErrOIDCUnavailable)
+		return
+	}
+	idToken, err := p.exchange(ctx, doc, c.Query("code"), state.Verifier)
+	if err != nil {
+		logger.WarnContext(ctx, "OIDC code exchange failed", "provider", p.cfg.Name, "error", err)
+		respondError(c, ErrOIDCExchange)
+		return
+	}
+	claims, err := p.verifyIDToken(ctx, doc, idToken, state.Nonce)
+	if err != nil {
+		logger.WarnContext(ctx, "OIDC id token rejected", "provider", p.cfg.Name, "error", err)
+		respondError(c, ErrInvalidIDToken)
+		return
+	}
+
+	user, err := linkOIDCIdentity(p.cfg.Name, claims)
+	if err != nil {
+		respondError(c, classifyError(err, "Login failed"))
+		return
+	}
+
+	// 启用了两步验证的用户同样需要完成第二步
+	if user.TOTPEnabledAt != nil {
+		startTwoFactorLogin(c, user)
+		return
+	}
+	recordLoginAttempt(c, &user.ID, normalizeEmail(user.Email), true, "")
+
+	pair, err := IssueTokenPair(user)
+	if err != nil {
+		respondError(c, InternalError("Failed to generate token", err))
+		return
+	}
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		User:         user,
+	})
+}
+
+// linkOIDCIdentity 查找外部身份关联的用户
+// 第一次登录时按提供方验证过的邮箱关联已有用户，没有则创建新用户
+// 已有用户的邮箱未验证时拒绝关联：账号可能是别人抢先用这个邮箱注册的，关联后对方仍能用自己的密码登录
+func linkOIDCIdentity(provider string, claims *idTokenClaims) (*model.User, error) {
+	var user model.User
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var identity model.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]any{"email": claims.Email, "last_login_at": now}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// 只信任提供方验证过的邮箱，否则可能被用来接管同邮箱的账号
+		if claims.Email == "" || !bool(claims.EmailVerified) {
+			return ErrOIDCEmailUnverified
+		}
+		email := normalizeEmail(claims.Email)
+		err = tx.Where("LOWER(email) = ?", email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := createOIDCUser(tx, &user, email, claims.PreferredUsername); err != nil {
+				return err
+			}
+			if err := promoteVerifiedAdmin(tx, &user); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case user.EmailVerifiedAt == nil:
+			return ErrOIDCAccountUnverified
+		}
+
+		return tx.Create(&model.UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// createOIDCUser 为外部身份创建用户
+// 密码设为随机值，需要密码登录时可以通过找回密码设置
+func createOIDCUser(tx *gorm.DB, user *model.User, email, preferred string) error {
+	password, err := randomString(32)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	base := preferred
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	username, err := uniqueUsername(tx, base)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	*user = model.User{
+		Username:        username,
+		Email:           email,
+		Password:        string(hash),
+		Role:            DefaultRole,
+		EmailVerifiedAt: &now,
+	}
+	return tx.Create(user).Error
+}
+
+// uniqueUsername 在base后追加数字，直到找到未被使用的用户名
+func uniqueUsername(tx *gorm.DB, base string) (string, error) {
+	if base = strings.TrimSpace(base); base == "" {
+		base = "user"
+	}
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		var count int64
+		if err := tx.Model(&model.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	suffix, err := randomString(6)
+	if err != nil {
+		return "", err
+	}
+	return base + "-" + suffix, nil
+}
+
+// listIdentities GET /api/profile/identities
+func listIdentities(c *gin.Context) {
+	userID, _ := GetCurrentUserID(c)
+	var identities []model.UserIdentity
+	if err := GetDB().Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		respondError(c, InternalError("Failed to load identities", err))
+		return
+	}
+	respondData(c, http.StatusOK, identities)
+}
@@ -0,0 +1,185 @@
+package blog
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	model "job/blog/Model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// fakeOIDCProvider 进程内的OIDC身份提供方，授权请求直接同意并跳回回调地址
+type fakeOIDCProvider struct {
+	*httptest.Server
+	key *SigningKey
+
+	mu            sync.Mutex
+	codes         map[string]url.Values // 授权码对应的授权请求参数
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := generateSigningKey(AlgRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeOIDCProvider{key: key, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                p.URL,
+			AuthorizationEndpoint: p.URL + "/authorize",
+			TokenEndpoint:         p.URL + "/token",
+			JWKSURI:               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(gin.H{"keys": []JWK{key.JWK()}})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		code := uuid.NewString()
+		p.mu.Lock()
+		p.codes[code] = q
+		p.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// token 校验授权码、客户端密钥和PKCE，签发ID Token
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	id, secret, _ := r.BasicAuth()
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || id != auth.Get("client_id") || secret != "s3cret" ||
+		r.PostForm.Get("redirect_uri") != auth.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(gin.H{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(p.key.Method, jwt.MapClaims{
+		"iss":            p.URL,
+		"aud":            auth.Get("client_id"),
+		"sub":            p.Subject,
+		"email":          p.Email,
+		"email_verified": p.EmailVerified,
+		"nonce":          auth.Get("nonce"),
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = p.key.ID
+	idToken, _ := token.SignedString(p.key.Private)
+	json.NewEncoder(w).Encode(gin.H{"access_token": "unused", "token_type": "Bearer", "id_token": idToken})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	r := newTestRouter(t)
+	idp := newFakeOIDCProvider(t)
+	InitOIDC(OIDCConfig{Providers: []OIDCProviderConfig{{Name: "fake", Issuer: idp.URL, ClientID: "blog", ClientSecret: "s3cret"}}})
+	t.Cleanup(func() { InitOIDC(OIDCConfig{}) })
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	// login 模拟浏览器：跳转到提供方，授权后带着状态Cookie访问回调地址
+	login := func() *httptest.ResponseRecorder {
+		t.Helper()
+		w := doRequest(r, http.MethodGet, "/auth/oidc/fake", "", nil)
+		if w.Code != http.StatusFound || len(w.Result().Cookies()) == 0 {
+			t.Fatalf("start: %d %s", w.Code, w.Body)
+		}
+		resp, err := browser.Get(w.Header().Get("Location"))
+		if err != nil || resp.StatusCode != http.StatusFound {
+			t.Fatalf("authorize: %v", err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+		req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+		req.AddCookie(w.Result().Cookies()[0])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	loginUserID := func() uint {
+		t.Helper()
+		w := login()
+		var res LoginResponse
+		json.Unmarshal(w.Body.Bytes(), &res)
+		claims, err := ParseToken(res.Token)
+		if w.Code != http.StatusOK || err != nil {
+			t.Fatalf("callback: %d %s", w.Code, w.Body)
+		}
+		return claims.UserID
+	}
+
+	// 已有用户占用了用户名alice，新用户需要换一个用户名
+	existing := model.User{Username: "alice", Email: "dave@example.com", Password: "x"}
+	GetDB().Create(&existing)
+
+	idp.Subject, idp.Email, idp.EmailVerified = "alice-123", "alice@example.com", true
+	first := loginUserID()
+	if second := loginUserID(); second != first {
+		t.Fatalf("same identity mapped to different users: %d, %d", first, second)
+	}
+
+	// 邮箱未验证的已有账号可能是别人抢先注册的，不能关联
+	idp.Subject, idp.Email = "dave-456", "dave@example.com"
+	if w := login(); w.Code != http.StatusConflict {
+		t.Fatalf("link to unverified account: %d %s", w.Code, w.Body)
+	}
+
+	// 已有账号按验证过的邮箱关联
+	GetDB().Model(&existing).Update("email_verified_at", time.Now())
+	if id := loginUserID(); id != existing.ID {
+		t.Fatalf("identity not linked to existing user: got %d, want %d", id, existing.ID)
+	}
+	var created model.User
+	GetDB().First(&created, first)
+	if created.Username != "alice2" || created.EmailVerifiedAt == nil {
+		t.Fatalf("unexpected new user: %+v", created)
+	}
+	var identities int64
+	GetDB().Model(&model.UserIdentity{}).Count(&identities)
+	if identities != 2 {
+		t.Fatalf("identities = %d, want 2", identities)
+	}
+
+	idp.Subject, idp.Email, idp.EmailVerified = "eve-789", "eve@example.com", false
+	if w := login(); w.Code != http.StatusForbidden {
+		t.Fatalf("unverified email: %d %s", w.Code, w.Body)
+	}
+
+	// 没有状态Cookie或state不匹配时拒绝回调
+	if w := doRequest(r, http.MethodGet, "/auth/oidc/fake/callback?code=x&state=y", "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("callback without state cookie: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/auth/oidc/unknown", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown provider: %d", w.Code)
+	}
+}
@@ -0,0 +1,287 @@
+package blog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 分页参数默认值
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidQuery 分页、排序或过滤参数不合法
+var ErrInvalidQuery = ValidationError("invalid_query", "Invalid query")
+
+// invalidQuery 构造参数错误，id是消息目录中的说明
+func invalidQuery(id string, args ...any) error {
+	return ErrInvalidQuery.WithDetail(id, args...)
+}
+
+// Pagination 分页信息
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"` // 游标分页时为空
+	Size       int    `json:"size"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// PageLinks 上一页/下一页链接
+type PageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Envelope /api 下成功响应的统一格式：资源放在data中，列表带上pagination和links，部分操作附带提示信息
+type Envelope struct {
+	Data       any         `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Links      *PageLinks  `json:"links,omitempty"`
+	Message    string      `json:"message,omitempty"`
+}
+
+// respondData 以统一格式返回单个资源或不分页的列表
+func respondData(c *gin.Context, status int, data any) {
+	c.JSON(status, Envelope{Data: data})
+}
+
+// respondPage 以统一格式返回一页数据
+func respondPage(c *gin.Context, data any, pagination Pagination, links PageLinks) {
+	c.JSON(http.StatusOK, Envelope{Data: data, Pagination: &pagination, Links: &links})
+}
+
+// PageResult 分页结果
+type PageResult[T any] struct {
+	Items      []T
+	Pagination Pagination
+	Links      PageLinks
+}
+
+// pageCursor 游标内容，对客户端不透明
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+	Sort      string    `json:"s"`           // 游标只能用于生成它的排序方式
+	Prev      bool      `json:"p,omitempty"` // 向前翻页
+}
+
+func encodeCursor(cur pageCursor) string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var cur pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, invalidQuery("invalid_query.malformed_cursor")
+	}
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return cur, invalidQuery("invalid_query.malformed_cursor")
+	}
+	return cur, nil
+}
+
+// parseSort 解析排序参数，"-" 前缀表示降序
+func parseSort(sort string, allowed []string) (column string, desc bool, err error) {
+	column, desc = strings.CutPrefix(sort, "-")
+	if !slices.Contains(allowed, column) {
+		return "", false, invalidQuery("invalid_query.sort", strings.Join(allowed, ", "), sort)
+	}
+	return column, desc, nil
+}
+
+// keysetSortable 只有按创建时间或ID排序时支持游标分页
+func keysetSortable(column string) bool {
+	return column == "created_at" || column == "id"
+}
+
+// parseDateParam 解析日期参数，支持 2006-01-02 和 RFC3339 两种格式
+// endOfDay为true时，纯日期会取当天结束时间
+func parseDateParam(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return nil, invalidQuery("invalid_query.date", name)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// applyCommonFilters 按作者和创建时间范围过滤
+func applyCommonFilters(c *gin.Context, q *gorm.DB) (*gorm.DB, error) {
+	if author := c.Query("author"); author != "" {
+		id, err := strconv.Atoi(author)
+		if err != nil {
+			return nil, invalidQuery("invalid_query.author")
+		}
+		q = q.Where("user_id = ?", id)
+	}
+	from, err := parseDateParam(c, "from", false)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil {
+		q = q.Where("created_at >= ?", *from)
+	}
+	to, err := parseDateParam(c, "to", true)
+	if err != nil {
+		return nil, err
+	}
+	if to != nil {
+		q = q.Where("created_at <= ?", *to)
+	}
+	return q, nil
+}
+
+// paginate 对查询进行分页
+// 默认使用 page/size 偏移分页；请求中带有 cursor 参数（可以为空）时使用基于 (created_at, id) 的游标分页
+// key 返回记录的创建时间和ID，用于生成游标
+func paginate[T any](c *gin.Context, q *gorm.DB, sorts []string, defaultSort string, key func(*T) (time.Time, uint)) (*PageResult[T], error) {
+	size := DefaultPageSize
+	if v := c.Query("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			return nil, invalidQuery("invalid_query.size", MaxPageSize)
+		}
+		size = n
+	}
+	sort := c.DefaultQuery("sort", defaultSort)
+	column, desc, err := parseSort(sort, sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	result := &PageResult[T]{Pagination: Pagination{Total: total, Size: size, Sort: sort}}
+	rawCursor, cursorMode := c.GetQuery("cursor")
+	if !cursorMode {
+		page := 1
+		if v := c.Query("page"); v != "" {
+			page, err = strconv.Atoi(v)
+			if err != nil || page < 1 {
+				return nil, invalidQuery("invalid_query.page")
+			}
+		}
+		if err := q.Order(orderClause(column, desc)).Offset((page - 1) * size).Limit(size).Find(&result.Items).Error; err != nil {
+			return nil, err
+		}
+		result.Pagination.Page = page
+		if int64(page*size) < total {
+			result.Links.Next = pageURL(c, map[string]string{"page": strconv.Itoa(page + 1)})
+		}
+		if page > 1 {
+			result.Links.Prev = pageURL(c, map[string]string{"page": strconv.Itoa(page - 1)})
+		}
+		return result, nil
+	}
+
+	if !keysetSortable(column) {
+		return nil, invalidQuery("invalid_query.cursor_sort")
+	}
+	var cur *pageCursor
+	if rawCursor != "" {
+		decoded, err := decodeCursor(rawCursor)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.Sort != sort {
+			return nil, invalidQuery("invalid_query.cursor_mismatch")
+		}
+		cur = &decoded
+	}
+
+	// 向前翻页时反转排序方向，查询完成后再把结果倒回来
+	backward := cur != nil && cur.Prev
+	if cur != nil {
+		op := ">"
+		if desc != backward {
+			op = "<"
+		}
+		if column == "created_at" {
+			q = q.Where(fmt.Sprintf("(created_at %s ? OR (created_at = ? AND id %s ?))", op, op), cur.CreatedAt, cur.CreatedAt, cur.ID)
+		} else {
+			q = q.Where(fmt.Sprintf("id %s ?", op), cur.ID)
+		}
+	}
+	if err := q.Order(orderClause(column, desc != backward)).Limit(size + 1).Find(&result.Items).Error; err != nil {
+		return nil, err
+	}
+	hasMore := len(result.Items) > size
+	if hasMore {
+		result.Items = result.Items[:size]
+	}
+	if backward {
+		slices.Reverse(result.Items)
+	}
+
+	hasNext, hasPrev := hasMore, cur != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	if n := len(result.Items); n > 0 {
+		if hasNext {
+			t, id := key(&result.Items[n-1])
+			result.Pagination.NextCursor = encodeCursor(pageCursor{CreatedAt: t, ID: id, Sort: sort})
+			result.Links.Next = pageURL(c, map[string]string{"cursor": result.Pagination.NextCursor})
+		}
+		if hasPrev {
+			t, id := key(&result.Items[0])
+			result.Pagination.PrevCursor = encodeCursor(pageCursor{CreatedAt: t, ID: id, Sort: sort, Prev: true})
+			result.Links.Prev = pageURL(c, map[string]string{"cursor": result.Pagination.PrevCursor})
+		}
+	}
+	return result, nil
+}
+
+// orderClause 生成排序子句，使用ID作为第二排序键保证顺序稳定
+func orderClause(column string, desc bool) string {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	if column == "id" {
+		return "id " + dir
+	}
+	return fmt.Sprintf("%s %s, id %s", column, dir, dir)
+}
+
+// pageURL 基于当前请求生成翻页链接
+func pageURL(c *gin.Context, set map[string]string) string {
+	u := *c.Request.URL
+	query := u.Query()
+	for k, v := range set {
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// abortQuery 参数错误返回400，其余返回500
+func abortQuery(c *gin.Context, err error) {
+	respondError(c, classifyError(err, "Failed to query"))
+}
@@ -0,0 +1,117 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type postPage struct {
+	Data       []model.Post `json:"data"`
+	Pagination Pagination   `json:"pagination"`
+	Links      PageLinks    `json:"links"`
+}
+
+func getPostPage(t *testing.T, r http.Handler, path, token string) postPage {
+	t.Helper()
+	w := doRequest(r, http.MethodGet, path, token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d: %s", path, w.Code, w.Body)
+	}
+	var page postPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func titles(posts []model.Post) string {
+	var s string
+	for _, p := range posts {
+		s += p.Title
+	}
+	return s
+}
+
+func seedPosts(t *testing.T, userID int, titles ...string) {
+	t.Helper()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range titles {
+		post := model.Post{Title: title, Content: "c", UserID: userID}
+		post.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := GetDB().Create(&post).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPostOffsetPagination(t *testing.T) {
+	r := newTestRouter(t)
+	user, token := loginAs(t, "author", RoleAuthor)
+	seedPosts(t, int(user.ID), "a", "b", "c", "d", "e")
+
+	page := getPostPage(t, r, "/api/posts?size=2&sort=created_at", token)
+	if titles(page.Data) != "ab" || page.Pagination.Total != 5 || page.Links.Prev != "" {
+		t.Fatalf("page 1 = %s %+v %+v", titles(page.Data), page.Pagination, page.Links)
+	}
+	page = getPostPage(t, r, page.Links.Next, token)
+	if titles(page.Data) != "cd" || page.Links.Prev == "" {
+		t.Fatalf("page 2 = %s %+v", titles(page.Data), page.Links)
+	}
+	page = getPostPage(t, r, page.Links.Next, token)
+	if titles(page.Data) != "e" || page.Links.Next != "" {
+		t.Fatalf("page 3 = %s %+v", titles(page.Data), page.Links)
+	}
+}
+
+func TestPostCursorPagination(t *testing.T) {
+	r := newTestRouter(t)
+	user, token := loginAs(t, "author", RoleAuthor)
+	seedPosts(t, int(user.ID), "a", "b", "c", "d", "e")
+
+	page := getPostPage(t, r, "/api/posts?size=2&cursor=", token)
+	if titles(page.Data) != "ed" || page.Pagination.PrevCursor != "" {
+		t.Fatalf("first page = %s %+v", titles(page.Data), page.Pagination)
+	}
+	page = getPostPage(t, r, page.Links.Next, token)
+	if titles(page.Data) != "cb" {
+		t.Fatalf("second page = %s", titles(page.Data))
+	}
+	last := getPostPage(t, r, page.Links.Next, token)
+	if titles(last.Data) != "a" || last.Links.Next != "" {
+		t.Fatalf("last page = %s %+v", titles(last.Data), last.Links)
+	}
+	back := getPostPage(t, r, last.Links.Prev, token)
+	if titles(back.Data) != "cb" {
+		t.Fatalf("prev page = %s", titles(back.Data))
+	}
+}
+
+func TestPostFilters(t *testing.T) {
+	r := newTestRouter(t)
+	alice, token := loginAs(t, "alice", RoleAuthor)
+	bob, _ := loginAs(t, "bob", RoleAuthor)
+	seedPosts(t, int(alice.ID), "go tips", "rust tips")
+	seedPosts(t, int(bob.ID), "go 100%")
+
+	if page := getPostPage(t, r, "/api/posts?q=go&sort=title", token); titles(page.Data) != "go 100%go tips" {
+		t.Fatalf("keyword filter = %s", titles(page.Data))
+	}
+	if page := getPostPage(t, r, "/api/posts?q=100%25", token); titles(page.Data) != "go 100%" {
+		t.Fatalf("escaped keyword filter = %s", titles(page.Data))
+	}
+	if page := getPostPage(t, r, fmt.Sprintf("/api/posts?author=%d", bob.ID), token); page.Pagination.Total != 1 {
+		t.Fatalf("author filter total = %d", page.Pagination.Total)
+	}
+	if page := getPostPage(t, r, "/api/posts?from=2024-12-31T23:00:00Z&to=2025-01-01T00:30:00Z", token); page.Pagination.Total != 2 {
+		t.Fatalf("date filter total = %d", page.Pagination.Total)
+	}
+	for _, path := range []string{"/api/posts?sort=password", "/api/posts?size=1000", "/api/posts?cursor=&sort=title"} {
+		if w := doRequest(r, http.MethodGet, path, token, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", path, w.Code)
+		}
+	}
+}
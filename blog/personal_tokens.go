@@ -0,0 +1,226 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 个人访问Token的前缀，AuthMiddleware据此区分个人访问Token和JWT
+const PATPrefix = "blog_pat_"
+
+// Scope 个人访问Token的授权范围，与用户权限共同生效：两者都满足时才能访问
+type Scope string
+
+const (
+	ScopePostsRead     Scope = "posts:read"
+	ScopePostsWrite    Scope = "posts:write"
+	ScopeCommentsRead  Scope = "comments:read"
+	ScopeCommentsWrite Scope = "comments:write"
+	ScopeProfileRead   Scope = "profile:read"
+	ScopeUsersWrite    Scope = "users:write"
+)
+
+// AllScopes 全部授权范围
+var AllScopes = []Scope{
+	ScopePostsRead, ScopePostsWrite,
+	ScopeCommentsRead, ScopeCommentsWrite,
+	ScopeProfileRead, ScopeUsersWrite,
+}
+
+// 权限对应的授权范围，RequirePermission同时检查对应的授权范围
+var permissionScopes = map[Permission]Scope{
+	PermPostRead:        ScopePostsRead,
+	PermPostCreate:      ScopePostsWrite,
+	PermPostModerate:    ScopePostsWrite,
+	PermCommentRead:     ScopeCommentsRead,
+	PermCommentCreate:   ScopeCommentsWrite,
+	PermCommentModerate: ScopeCommentsWrite,
+	PermUserManage:      ScopeUsersWrite,
+}
+
+// 同一个Token的最后使用时间最多每分钟更新一次
+const patTouchInterval = time.Minute
+
+// 个人访问Token相关错误
+var (
+	ErrInvalidPAT    = UnauthorizedError("invalid_personal_token", "Invalid, expired or revoked personal access token")
+	ErrPATNotFound   = NotFoundError("token_not_found", "Token not found")
+	ErrSessionOnly   = ForbiddenError("session_required", "Personal access tokens cannot be used for this endpoint")
+	ErrScopeRequired = ForbiddenError("scope_required", "Token scope is required")
+	ErrUnknownScope  = ValidationError("unknown_scope", "Unknown scope")
+)
+
+// 创建个人访问Token请求结构
+type CreatePATRequest struct {
+	Name          string   `json:"name" binding:"required,max=64"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 不填表示永不过期
+}
+
+// 个人访问Token响应结构，token明文只在创建时返回一次
+type PATResponse struct {
+	*model.PersonalAccessToken
+	Scopes []string `json:"scopes"`
+	Token  string   `json:"token,omitempty"`
+}
+
+func newPATResponse(pat *model.PersonalAccessToken) PATResponse {
+	return PATResponse{PersonalAccessToken: pat, Scopes: splitList(pat.Scopes)}
+}
+
+// IsPersonalAccessToken 判断Bearer凭据是否是个人访问Token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PATPrefix)
+}
+
+// AuthenticatePAT 校验个人访问Token，返回与JWT相同结构的Claims和Token的授权范围
+// 权限取用户当前的权限，角色变化后立即生效
+func AuthenticatePAT(raw string) (*CustomClaims, []string, error) {
+	var pat model.PersonalAccessToken
+	if err := GetDB().Where("token_hash = ?", hashRefreshToken(raw)).First(&pat).Error; err != nil {
+		return nil, nil, ErrInvalidPAT
+	}
+	now := time.Now()
+	if pat.RevokedAt != nil || (pat.ExpiresAt != nil && now.After(*pat.ExpiresAt)) {
+		return nil, nil, ErrInvalidPAT
+	}
+	var user model.User
+	if err := GetDB().First(&user, pat.UserID).Error; err != nil {
+		return nil, nil, ErrInvalidPAT
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) >= patTouchInterval {
+		err := GetDB().Model(&model.PersonalAccessToken{}).
+			Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", pat.ID, now.Add(-patTouchInterval)).
+			Update("last_used_at", now).Error
+		if err != nil {
+			logger.Warn("Failed to update token last used time", "pat_id", pat.ID, "error", err)
+		}
+	}
+
+	claims := &CustomClaims{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: UserPermissions(&user),
+	}
+	return claims, splitList(pat.Scopes), nil
+}
+
+// HasScope 检查当前请求的授权范围，通过登录会话（JWT）认证的请求不受限制
+func HasScope(c *gin.Context, scope Scope) bool {
+	scopes, ok := c.Get("scopes")
+	if !ok {
+		return true
+	}
+	list, _ := scopes.([]string)
+	return slices.Contains(list, string(scope))
+}
+
+// RequireScope 授权范围校验中间件，用于没有对应权限但需要限制个人访问Token的路由
+func RequireScope(scopes ...Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, scope := range scopes {
+			if !HasScope(c, scope) {
+				respondError(c, ErrScopeRequired.WithDetail("scope_required.scope", scope))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession 只允许登录会话访问，用于管理Token、两步验证等账号安全相关的路由
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("scopes"); ok {
+			respondError(c, ErrSessionOnly)
+			return
+		}
+		c.Next()
+	}
+}
+
+// listPATs GET /api/tokens
+func listPATs(c *gin.Context) {
+	userID, _ := GetCurrentUserID(c)
+	var tokens []model.PersonalAccessToken
+	if err := GetDB().Where("user_id = ? AND revoked_at IS NULL", userID).Order("id DESC").Find(&tokens).Error; err != nil {
+		respondError(c, InternalError("Failed to load tokens", err))
+		return
+	}
+	res := make([]PATResponse, len(tokens))
+	for i := range tokens {
+		res[i] = newPATResponse(&tokens[i])
+	}
+	respondData(c, http.StatusOK, res)
+}
+
+// createPAT POST /api/tokens
+func createPAT(c *gin.Context) {
+	var req CreatePATRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	scopes := []string{}
+	for _, s := range req.Scopes {
+		if !slices.Contains(AllScopes, Scope(s)) {
+			respondError(c, ErrUnknownScope.WithDetail("unknown_scope.scope", s))
+			return
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		respondError(c, InternalError("Failed to generate token", err))
+		return
+	}
+	raw := PATPrefix + secret
+	userID, _ := GetCurrentUserID(c)
+	pat := model.PersonalAccessToken{
+		UserID:      uint(userID),
+		Name:        req.Name,
+		TokenHash:   hashRefreshToken(raw),
+		TokenPrefix: raw[:len(PATPrefix)+4],
+		Scopes:      strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		pat.ExpiresAt = &expiresAt
+	}
+	if err := GetDB().Create(&pat).Error; err != nil {
+		respondError(c, InternalError("Failed to create token", err))
+		return
+	}
+
+	res := newPATResponse(&pat)
+	res.Token = raw
+	respondData(c, http.StatusCreated, res)
+}
+
+// revokePAT DELETE /api/tokens/:id
+func revokePAT(c *gin.Context) {
+	userID, _ := GetCurrentUserID(c)
+	res := GetDB().Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		respondError(c, InternalError("Failed to revoke token", res.Error))
+		return
+	}
+	if res.RowsAffected == 0 {
+		respondError(c, ErrPATNotFound)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
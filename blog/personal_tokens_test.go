@@ -0,0 +1,127 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPersonalAccessTokens(t *testing.T) {
+	r := newTestRouter(t)
+	user, session := loginAs(t, "bot", RoleAuthor)
+
+	if w := doRequest(r, http.MethodPost, "/api/tokens", session, gin.H{"name": "ci", "scopes": []string{"posts:everything"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown scope: %d", w.Code)
+	}
+	w := doRequest(r, http.MethodPost, "/api/tokens", session, gin.H{"name": "ci", "scopes": []string{"posts:read", "posts:write"}, "expires_in_days": 30})
+	var created PATResponse
+	decodeData(w, &created)
+	if w.Code != http.StatusCreated || !strings.HasPrefix(created.Token, PATPrefix) || created.ExpiresAt == nil {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	pat := created.Token
+
+	// 授权范围内的路由可以访问
+	w = doRequest(r, http.MethodPost, "/api/posts", pat, gin.H{"title": "From CI", "content": "c"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create post with token: %d %s", w.Code, w.Body)
+	}
+	var post model.Post
+	decodeData(w, &post)
+	if post.UserID != int(user.ID) {
+		t.Fatalf("post owner = %d, want %d", post.UserID, user.ID)
+	}
+	if w := doRequest(r, http.MethodDelete, fmt.Sprintf("/api/posts/%d", post.ID), pat, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete own post with token: %d %s", w.Code, w.Body)
+	}
+
+	// 超出授权范围或账号安全相关的路由被拒绝
+	for _, path := range []string{"/api/profile", "/api/posts/1/comments"} {
+		if w := doRequest(r, http.MethodGet, path, pat, nil); w.Code != http.StatusForbidden {
+			t.Fatalf("GET %s with token: %d", path, w.Code)
+		}
+	}
+	if w := doRequest(r, http.MethodPost, "/api/tokens", pat, gin.H{"name": "more", "scopes": []string{"users:write"}}); w.Code != http.StatusForbidden {
+		t.Fatalf("token minted by token: %d", w.Code)
+	}
+
+	var stored model.PersonalAccessToken
+	GetDB().First(&stored, created.ID)
+	if stored.LastUsedAt == nil || stored.TokenHash == pat {
+		t.Fatalf("unexpected stored token: %+v", stored)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/tokens", session, nil)
+	var list []PATResponse
+	decodeData(w, &list)
+	if len(list) != 1 || list[0].Token != "" || len(list[0].Scopes) != 2 {
+		t.Fatalf("list: %s", w.Body)
+	}
+
+	if w := doRequest(r, http.MethodDelete, fmt.Sprintf("/api/tokens/%d", created.ID), session, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/posts", pat, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token: %d", w.Code)
+	}
+}
+
+func TestRevokeUserSessionsRevokesPersonalTokens(t *testing.T) {
+	r := newTestRouter(t)
+	user, session := loginAs(t, "victim", RoleAuthor)
+	w := doRequest(r, http.MethodPost, "/api/tokens", session, gin.H{"name": "ci", "scopes": []string{"posts:read"}})
+	var created PATResponse
+	decodeData(w, &created)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+
+	// 重置密码后之前创建的个人访问Token同样失效
+	if err := RevokeUserSessions(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/posts", created.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("token after password reset: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/posts", session, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("session after password reset: %d", w.Code)
+	}
+}
+
+func TestReadOnlyTokenCannotReact(t *testing.T) {
+	r := newTestRouter(t)
+	user, session := loginAs(t, "reader", RoleAuthor)
+	post := model.Post{Title: "t", Content: "c", UserID: int(user.ID), Status: model.PostStatusPublished}
+	GetDB().Create(&post)
+	comment := model.Comment{Content: "c", PostID: int(post.ID), UserID: int(user.ID)}
+	GetDB().Create(&comment)
+
+	token := func(scopes ...string) string {
+		w := doRequest(r, http.MethodPost, "/api/tokens", session, gin.H{"name": "bot", "scopes": scopes})
+		var created PATResponse
+		decodeData(w, &created)
+		return created.Token
+	}
+	readOnly := token("posts:read", "comments:read")
+	readWrite := token("posts:read", "posts:write", "comments:read", "comments:write")
+
+	paths := []string{
+		fmt.Sprintf("/api/posts/%d/like", post.ID),
+		fmt.Sprintf("/api/posts/%d/bookmark", post.ID),
+		fmt.Sprintf("/api/comments/%d/reactions/heart", comment.ID),
+	}
+	for _, path := range paths {
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			if w := doRequest(r, method, path, readOnly, nil); w.Code != http.StatusForbidden {
+				t.Errorf("%s %s with read-only token: %d", method, path, w.Code)
+			}
+			if w := doRequest(r, method, path, readWrite, nil); w.Code >= http.StatusBadRequest {
+				t.Errorf("%s %s with write token: %d %s", method, path, w.Code, w.Body)
+			}
+		}
+	}
+}
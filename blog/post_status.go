@@ -0,0 +1,177 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPostStatus     = ValidationError("invalid_post_status", "status must be one of draft, scheduled, published, archived")
+	ErrInvalidTransition     = ValidationError("invalid_status_transition", "status transition is not allowed")
+	ErrPublishAtRequired     = ValidationError("publish_at_required", "publish_at is required for scheduled posts")
+	ErrPublishAtNotInFuture  = ValidationError("publish_at_not_in_future", "publish_at must be in the future")
+	ErrPublishAtNotScheduled = ValidationError("publish_at_not_scheduled", "publish_at can only be set on scheduled posts")
+)
+
+// postTransitions 文章状态流转规则，空状态表示新建文章，状态不变时（如修改定时发布时间）总是允许
+var postTransitions = map[string][]string{
+	"":                        {model.PostStatusDraft, model.PostStatusScheduled, model.PostStatusPublished},
+	model.PostStatusDraft:     {model.PostStatusScheduled, model.PostStatusPublished, model.PostStatusArchived},
+	model.PostStatusScheduled: {model.PostStatusDraft, model.PostStatusPublished, model.PostStatusArchived},
+	model.PostStatusPublished: {model.PostStatusDraft, model.PostStatusArchived},
+	model.PostStatusArchived:  {model.PostStatusDraft, model.PostStatusPublished},
+}
+
+// IsValidPostStatus 判断是否为合法的文章状态
+func IsValidPostStatus(status string) bool {
+	switch status {
+	case model.PostStatusDraft, model.PostStatusScheduled, model.PostStatusPublished, model.PostStatusArchived:
+		return true
+	}
+	return false
+}
+
+// setPostStatus 校验状态流转并更新文章的状态和发布时间
+// status为空时，新文章指定了publishAt视为定时发布，否则直接发布
+func setPostStatus(post *model.Post, status string, publishAt *time.Time, now time.Time) error {
+	if status == "" {
+		status = model.PostStatusPublished
+		if publishAt != nil {
+			status = model.PostStatusScheduled
+		}
+	}
+	if !IsValidPostStatus(status) {
+		return ErrInvalidPostStatus
+	}
+	if post.Status != status && !slices.Contains(postTransitions[post.Status], status) {
+		return ErrInvalidTransition.WithDetail("invalid_status_transition.detail", post.Status, status)
+	}
+
+	switch status {
+	case model.PostStatusScheduled:
+		if publishAt == nil {
+			return ErrPublishAtRequired
+		}
+		if !publishAt.After(now) {
+			return ErrPublishAtNotInFuture
+		}
+		post.PublishAt = publishAt
+	case model.PostStatusPublished:
+		if publishAt != nil {
+			return ErrPublishAtNotScheduled
+		}
+		if post.Status != model.PostStatusPublished {
+			post.PublishedAt = &now
+		}
+		post.PublishAt = nil
+	default:
+		if publishAt != nil {
+			return ErrPublishAtNotScheduled
+		}
+		post.PublishAt = nil
+	}
+	post.Status = status
+	return nil
+}
+
+// visiblePosts 限制查询范围：有审核权限的用户可以看到全部文章，其他用户只能看到已发布的文章和自己的文章
+func visiblePosts(c *gin.Context, q *gorm.DB) *gorm.DB {
+	if HasPermission(c, PermPostModerate) {
+		return q
+	}
+	if userID, ok := GetCurrentUserID(c); ok {
+		return q.Where("(status = ? OR user_id = ?)", model.PostStatusPublished, userID)
+	}
+	return q.Where("status = ?", model.PostStatusPublished)
+}
+
+// visiblePost 按ID查找当前用户可见的文章
+func visiblePost(c *gin.Context, id int) (*model.Post, error) {
+	var post model.Post
+	if err := visiblePosts(c, GetDB()).Preload("Tags").Preload("Categories").First(&post, id).Error; err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// PublishDuePosts 发布所有到期的定时文章，返回发布的数量
+// 使用带状态条件的更新，多个实例同时运行时每篇文章只会被发布一次
+func PublishDuePosts(now time.Time) (int, error) {
+	var due []model.Post
+	err := GetDB().Where("status = ? AND publish_at <= ?", model.PostStatusScheduled, now).
+		Order("publish_at").Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range due {
+		res := GetDB().Model(&due[i]).Where("status = ?", model.PostStatusScheduled).Updates(map[string]any{
+			"status":       model.PostStatusPublished,
+			"publish_at":   nil,
+			"published_at": now,
+		})
+		if res.Error != nil {
+			return published, res.Error
+		}
+		published += int(res.RowsAffected)
+	}
+	return published, nil
+}
+
+var (
+	schedulerMu   sync.Mutex
+	schedulerStop chan struct{}
+)
+
+// TokenPurgeInterval 后台任务清理过期刷新Token、吊销记录和旧登录记录的间隔
+var TokenPurgeInterval = time.Hour
+
+// StartScheduler 启动后台定时任务，按interval检查并发布到期的定时文章，并按TokenPurgeInterval清理过期的Token和旧登录记录
+func StartScheduler(interval time.Duration) {
+	StopScheduler()
+	stop := make(chan struct{})
+	schedulerMu.Lock()
+	schedulerStop = stop
+	schedulerMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var lastPurge time.Time
+		for {
+			select {
+			case now := <-ticker.C:
+				if _, err := PublishDuePosts(now); err != nil {
+					logger.Error("Publishing scheduled posts failed", "error", err)
+				}
+				if now.Sub(lastPurge) >= TokenPurgeInterval {
+					if err := PurgeExpiredTokens(); err != nil {
+						logger.Error("Purging expired tokens failed", "error", err)
+					}
+					if err := PurgeLoginAttempts(now); err != nil {
+						logger.Error("Purging login attempts failed", "error", err)
+					}
+					lastPurge = now
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopScheduler 停止后台定时任务
+func StopScheduler() {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if schedulerStop != nil {
+		close(schedulerStop)
+		schedulerStop = nil
+	}
+}
@@ -0,0 +1,153 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDraftVisibleOnlyToOwnerAndModerators(t *testing.T) {
+	r := newTestRouter(t)
+	author, authorToken := loginAs(t, "author", RoleAuthor)
+	_, otherToken := loginAs(t, "other", RoleAuthor)
+	_, editorToken := loginAs(t, "editor", RoleEditor)
+
+	draft := model.Post{Title: "draft", Content: "c", UserID: int(author.ID), Status: model.PostStatusDraft}
+	GetDB().Create(&draft)
+	seedPosts(t, int(author.ID), "published")
+
+	path := fmt.Sprintf("/api/posts/%d", draft.ID)
+	if w := doRequest(r, http.MethodGet, path, otherToken, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("other status = %d, want 404", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/get_post?id="+fmt.Sprint(draft.ID), otherToken, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("legacy other status = %d, want 404", w.Code)
+	}
+	for _, token := range []string{authorToken, editorToken} {
+		if w := doRequest(r, http.MethodGet, path, token, nil); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+	}
+
+	if got := titles(getPostPage(t, r, "/api/posts?sort=id", otherToken).Data); got != "published" {
+		t.Fatalf("other sees %q", got)
+	}
+	if got := titles(getPostPage(t, r, "/api/posts?sort=id", authorToken).Data); got != "draftpublished" {
+		t.Fatalf("author sees %q", got)
+	}
+	if got := titles(getPostPage(t, r, "/api/posts?status=draft", editorToken).Data); got != "draft" {
+		t.Fatalf("editor drafts = %q", got)
+	}
+}
+
+func TestScheduledPostIsPublishedWhenDue(t *testing.T) {
+	r := newTestRouter(t)
+	if err := InitSearch(NewInvertedIndex()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { model.SearchHook = nil })
+	_, authorToken := loginAs(t, "author", RoleAuthor)
+	_, otherToken := loginAs(t, "other", RoleAuthor)
+
+	publishAt := time.Now().Add(time.Hour)
+	w := doRequest(r, http.MethodPost, "/api/posts", authorToken, gin.H{"title": "future", "content": "scheduled", "publish_at": publishAt})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body)
+	}
+	var post model.Post
+	GetDB().Last(&post)
+	if post.Status != model.PostStatusScheduled {
+		t.Fatalf("status = %q, want scheduled", post.Status)
+	}
+	res, _ := searchIndex.Search(SearchQuery{Text: "future", Limit: 10})
+	if res.Total != 0 {
+		t.Fatalf("scheduled post indexed: %+v", res.Hits)
+	}
+
+	if n, err := PublishDuePosts(time.Now()); err != nil || n != 0 {
+		t.Fatalf("published %d early, err %v", n, err)
+	}
+	if n, err := PublishDuePosts(publishAt.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("PublishDuePosts = %d, %v", n, err)
+	}
+
+	post = model.Post{}
+	GetDB().Last(&post)
+	if post.Status != model.PostStatusPublished || post.PublishedAt == nil || post.PublishAt != nil {
+		t.Fatalf("post after publish = %+v", post)
+	}
+	if w := doRequest(r, http.MethodGet, fmt.Sprintf("/api/posts/%d", post.ID), otherToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("other status = %d, want 200", w.Code)
+	}
+	res, _ = searchIndex.Search(SearchQuery{Text: "future", Limit: 10})
+	if res.Total != 1 {
+		t.Fatalf("published post not indexed: %+v", res.Hits)
+	}
+}
+
+func TestPostStatusTransitions(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+	tests := []struct {
+		from, to  string
+		publishAt *time.Time
+		wantErr   bool
+	}{
+		{"", "", nil, false},
+		{"", model.PostStatusArchived, nil, true},
+		{model.PostStatusDraft, model.PostStatusScheduled, &future, false},
+		{model.PostStatusDraft, model.PostStatusScheduled, &past, true},
+		{model.PostStatusDraft, model.PostStatusScheduled, nil, true},
+		{model.PostStatusScheduled, model.PostStatusScheduled, &future, false},
+		{model.PostStatusPublished, model.PostStatusScheduled, &future, true},
+		{model.PostStatusPublished, model.PostStatusArchived, nil, false},
+		{model.PostStatusArchived, model.PostStatusPublished, nil, false},
+		{model.PostStatusDraft, "deleted", nil, true},
+	}
+	for _, tt := range tests {
+		post := model.Post{Status: tt.from}
+		err := setPostStatus(&post, tt.to, tt.publishAt, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q -> %q: err = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStatusChangeSyncsCommentIndex(t *testing.T) {
+	r := newTestRouter(t)
+	if err := InitSearch(NewInvertedIndex()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { model.SearchHook = nil })
+	author, token := loginAs(t, "author", RoleAuthor)
+
+	post := model.Post{Title: "t", Content: "c", UserID: int(author.ID), Status: model.PostStatusPublished}
+	GetDB().Create(&post)
+	GetDB().Create(&model.Comment{Content: "unique remark", PostID: int(post.ID), UserID: int(author.ID)})
+	commentHits := func() int64 {
+		res, _ := searchIndex.Search(SearchQuery{Text: "remark", Kind: SearchKindComment, Limit: 10})
+		return res.Total
+	}
+	if commentHits() != 1 {
+		t.Fatal("comment on published post not indexed")
+	}
+
+	path := fmt.Sprintf("/api/posts/%d", post.ID)
+	if w := doRequest(r, http.MethodPatch, path, token, gin.H{"status": model.PostStatusDraft}); w.Code != http.StatusOK {
+		t.Fatalf("unpublish: %d %s", w.Code, w.Body)
+	}
+	if n := commentHits(); n != 0 {
+		t.Fatalf("comments still indexed after unpublishing: %d", n)
+	}
+	if w := doRequest(r, http.MethodPatch, path, token, gin.H{"status": model.PostStatusPublished}); w.Code != http.StatusOK {
+		t.Fatalf("republish: %d %s", w.Code, w.Body)
+	}
+	if n := commentHits(); n != 1 {
+		t.Fatalf("comments not reindexed after publishing: %d", n)
+	}
+}
@@ -0,0 +1,256 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 创建文章请求结构
+type CreatePostRequest struct {
+	Title      string     `json:"title" binding:"required"`
+	Content    string     `json:"content" binding:"required"`
+	Status     string     `json:"status"`     // 为空时直接发布，指定了publish_at时为定时发布
+	PublishAt  *time.Time `json:"publish_at"` // 定时发布时间，RFC3339格式
+	Tags       []string   `json:"tags"`       // 标签名，不存在时自动创建
+	Categories []string   `json:"categories"` // 分类slug，分类必须已经存在
+}
+
+// 更新文章请求结构，只更新传入的字段
+type UpdatePostRequest struct {
+	Title      *string    `json:"title" binding:"omitempty,min=1"`
+	Content    *string    `json:"content" binding:"omitempty,min=1"`
+	Status     *string    `json:"status"`
+	PublishAt  *time.Time `json:"publish_at"`
+	Tags       *[]string  `json:"tags"`       // 传入时替换全部标签
+	Categories *[]string  `json:"categories"` // 传入时替换全部分类
+}
+
+// 文章相关错误
+var (
+	ErrPostNotFound = NotFoundError("post_not_found", "Post not found")
+	ErrNotPostOwner = ForbiddenError("not_post_owner", "You are not the owner of this post")
+)
+
+// paramID 解析路径中的资源ID，不合法时返回400
+func paramID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		respondError(c, ErrInvalidID)
+		return 0, false
+	}
+	return id, true
+}
+
+// findPost 按路径ID查找当前用户可见的文章，不存在或不可见时返回404
+func findPost(c *gin.Context) (*model.Post, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+	post, err := visiblePost(c, id)
+	if err != nil {
+		respondError(c, ErrPostNotFound)
+		return nil, false
+	}
+	return post, true
+}
+
+// 文章列表支持的排序字段
+var postSorts = []string{"created_at", "updated_at", "id", "title"}
+
+// escapeLike 转义LIKE中的通配符，配合 ESCAPE '!' 使用
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// queryPosts 按请求参数过滤、排序并分页查询文章
+// 支持参数：page、size、cursor、sort、author、from、to、q（标题关键字）、status、tag、category
+// 只返回当前用户可见的文章
+func queryPosts(c *gin.Context) (*PageResult[model.Post], error) {
+	q, err := applyCommonFilters(c, visiblePosts(c, GetDB().Model(&model.Post{})))
+	if err != nil {
+		return nil, err
+	}
+	q = applyTaxonomyFilters(c, q).Preload("Tags").Preload("Categories")
+	if kw := c.Query("q"); kw != "" {
+		q = q.Where("title LIKE ? ESCAPE '!'", "%"+escapeLike(kw)+"%")
+	}
+	if status := c.Query("status"); status != "" {
+		if !IsValidPostStatus(status) {
+			return nil, invalidQuery("invalid_query.status")
+		}
+		q = q.Where("status = ?", status)
+	}
+	return paginate(c, q, postSorts, "-created_at", func(p *model.Post) (time.Time, uint) {
+		return p.CreatedAt, p.ID
+	})
+}
+
+// listPosts GET /api/posts
+func listPosts(c *gin.Context) {
+	format, err := contentFormat(c)
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	res, err := queryPosts(c)
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	formatPosts(format, res.Items)
+	respondPage(c, res.Items, res.Pagination, res.Links)
+}
+
+// storePost POST /api/posts
+func storePost(c *gin.Context) {
+	var req CreatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	userID, _ := GetCurrentUserID(c)
+	post := model.Post{Title: req.Title, Content: req.Content, UserID: userID}
+	if err := setPostStatus(&post, req.Status, req.PublishAt, time.Now()); err != nil {
+		respondError(c, err)
+		return
+	}
+	err := inTransaction(func(tx *gorm.DB) error {
+		if err := savePost(tx, &post, userID, nil); err != nil {
+			return err
+		}
+		return setPostTaxonomy(tx, &post, &req.Tags, &req.Categories)
+	})
+	if err != nil {
+		respondError(c, classifyError(err, "Failed to create post"))
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/api/posts/%d", post.ID))
+	respondData(c, http.StatusCreated, post)
+}
+
+// showPost GET /api/posts/:id?format=markdown|html
+func showPost(c *gin.Context) {
+	format, err := contentFormat(c)
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	posts := []model.Post{*post}
+	formatPosts(format, posts)
+	respondData(c, http.StatusOK, posts[0])
+}
+
+// patchPost PATCH /api/posts/:id
+func patchPost(c *gin.Context) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	if !canModify(c, post.UserID, PermPostModerate) {
+		respondError(c, ErrNotPostOwner)
+		return
+	}
+	var req UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	userID, _ := GetCurrentUserID(c)
+	err := inTransaction(func(tx *gorm.DB) error {
+		// 在最新的文章上应用修改，避免覆盖并发的修改和定时发布
+		fresh, err := lockPost(tx, post.ID)
+		if err != nil {
+			return err
+		}
+		if err := applyPostUpdate(fresh, &req, time.Now()); err != nil {
+			return err
+		}
+		if err := savePost(tx, fresh, userID, nil); err != nil {
+			return err
+		}
+		if err := setPostTaxonomy(tx, fresh, req.Tags, req.Categories); err != nil {
+			return err
+		}
+		post = fresh
+		return nil
+	})
+	if err != nil {
+		respondError(c, classifyError(err, "Failed to update post"))
+		return
+	}
+	respondData(c, http.StatusOK, post)
+}
+
+// applyPostUpdate 把更新请求中传入的字段应用到文章上
+func applyPostUpdate(post *model.Post, req *UpdatePostRequest, now time.Time) error {
+	if req.Title != nil {
+		post.Title = *req.Title
+	}
+	if req.Content != nil {
+		post.Content = *req.Content
+	}
+	if req.Status == nil && req.PublishAt == nil {
+		return nil
+	}
+	status, publishAt := post.Status, req.PublishAt
+	if req.Status != nil {
+		status = *req.Status
+	} else {
+		// 只传publish_at表示定时发布或修改定时发布时间
+		status = model.PostStatusScheduled
+	}
+	if status == model.PostStatusScheduled && publishAt == nil {
+		publishAt = post.PublishAt
+	}
+	return setPostStatus(post, status, publishAt, now)
+}
+
+// destroyPost DELETE /api/posts/:id
+func destroyPost(c *gin.Context) {
+	post, ok := findPost(c)
+	if !ok {
+		return
+	}
+	if !canModify(c, post.UserID, PermPostModerate) {
+		respondError(c, ErrNotPostOwner)
+		return
+	}
+	if err := removePost(c, post); err != nil {
+		respondError(c, InternalError("Failed to delete post", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// removePost 删除文章和它的附件记录，提交后再删除附件在存储中的文件
+func removePost(c *gin.Context, post *model.Post) error {
+	var atts []model.Attachment
+	err := inTransaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", post.ID).Find(&atts).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("post_id = ?", post.ID).Delete(&model.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(post).Error
+	})
+	if err != nil {
+		return err
+	}
+	for i := range atts {
+		deleteAttachmentObjects(c, &atts[i])
+	}
+	return nil
+}
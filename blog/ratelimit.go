@@ -0,0 +1,251 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的限流存储
+const (
+	RateLimitMemory = "memory"
+	RateLimitRedis  = "redis"
+)
+
+// RateLimitConfig 限流配置，各路由的额度在代码中定义
+type RateLimitConfig struct {
+	Enabled bool        `yaml:"enabled"`
+	Driver  string      `yaml:"driver"` // 存储：memory（单实例）、redis（多实例共享）
+	Redis   RedisConfig `yaml:"redis"`
+}
+
+// RateLimit 令牌桶额度：每Per时间补充Requests个令牌，桶容量也是Requests
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// 各路由的限流额度
+var (
+	LoginRateLimit    = RateLimit{Requests: 10, Per: time.Minute}
+	RegisterRateLimit = RateLimit{Requests: 5, Per: time.Hour}
+	CommentRateLimit  = RateLimit{Requests: 10, Per: time.Minute}
+)
+
+// rate 每秒补充的令牌数
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // 桶中剩余的令牌数
+	Reset      time.Duration // 桶重新装满还需要的时间
+	RetryAfter time.Duration // 被拒绝时，下一个令牌可用还需要的时间
+}
+
+// bucketResult 根据取令牌后桶中的令牌数计算结果
+func bucketResult(allowed bool, tokens float64, limit RateLimit) RateLimitResult {
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) / limit.rate() * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	}
+	return res
+}
+
+// RateLimitStore 令牌桶存储，Take需要是原子的
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// 全局限流存储和开关，由InitRateLimit初始化
+var (
+	rateLimitStore   RateLimitStore = NewMemoryRateLimitStore()
+	rateLimitEnabled                = true
+)
+
+// InitRateLimit 根据配置初始化限流存储
+func InitRateLimit(cfg RateLimitConfig) error {
+	rateLimitEnabled = cfg.Enabled
+	switch cfg.Driver {
+	case RateLimitMemory:
+		rateLimitStore = NewMemoryRateLimitStore()
+	case RateLimitRedis:
+		client, err := NewRedisClient(cfg.Redis)
+		if err != nil {
+			return err
+		}
+		rateLimitStore = NewRedisRateLimitStore(client)
+	default:
+		return fmt.Errorf("unsupported rate limit driver %q", cfg.Driver)
+	}
+	return nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// MemoryRateLimitStore 进程内的令牌桶存储，只适合单实例部署
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore 创建进程内的令牌桶存储
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Requests), last: now, limit: limit}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+elapsed*limit.rate())
+		b.last = now
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketResult(allowed, b.tokens, limit), nil
+}
+
+// sweep 每分钟清理一次已经装满的桶，装满的桶和不存在的桶等价
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.rate() >= float64(b.limit.Requests) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// rateLimitScript 在Redis中原子地执行令牌桶算法
+// KEYS[1]：桶的key；ARGV：容量、每毫秒补充的令牌数、当前毫秒时间戳
+// 令牌数以字符串返回，避免Lua数字转换为整数时丢失小数
+const rateLimitScript = `
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// RedisRateLimitStore 基于Redis（或兼容协议的服务，如Valkey、KeyDB）的令牌桶存储，多实例共享额度
+type RedisRateLimitStore struct {
+	client *RedisClient
+}
+
+// NewRedisRateLimitStore 创建Redis令牌桶存储
+func NewRedisRateLimitStore(client *RedisClient) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	reply, err := s.client.Do(ctx, "EVAL", rateLimitScript, "1", key,
+		strconv.Itoa(limit.Requests),
+		strconv.FormatFloat(limit.rate()/1000, 'g', -1, 64),
+		strconv.FormatInt(now.UnixMilli(), 10))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	raw, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	return bucketResult(allowed == 1, tokens, limit), nil
+}
+
+// RateLimitKeyFunc 从请求中取出限流的主体
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP 按客户端IP限流
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser 按AuthMiddleware设置的用户ID限流，未登录时退化为按IP
+func KeyByUser(c *gin.Context) string {
+	if id, ok := GetCurrentUserID(c); ok {
+		return "user:" + strconv.Itoa(id)
+	}
+	return KeyByIP(c)
+}
+
+// ErrRateLimited 超过限流额度
+var ErrRateLimited = NewError(KindRateLimited, "rate_limited", "Too many requests, please try again later")
+
+// RateLimitMiddleware 令牌桶限流中间件，name区分不同路由的额度
+// 响应中带有RateLimit-Limit、RateLimit-Remaining、RateLimit-Reset头，超出额度时返回429和Retry-After
+// 存储出错时放行请求，限流不可用不应该导致服务不可用
+func RateLimitMiddleware(name string, limit RateLimit, key RateLimitKeyFunc) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Per.Seconds()))
+	return func(c *gin.Context) {
+		if !rateLimitEnabled {
+			c.Next()
+			return
+		}
+		res, err := rateLimitStore.Take(c.Request.Context(), "ratelimit:"+name+":"+key(c), limit, time.Now())
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Rate limit store error", "limit", name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			respondError(c, ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds 向上取整到秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
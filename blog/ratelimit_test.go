@@ -0,0 +1,221 @@
+package blog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	s := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 3, Per: time.Minute}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	for i := range 3 {
+		res, _ := s.Take(ctx, "k", limit, now)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+	}
+	res, _ := s.Take(ctx, "k", limit, now)
+	if res.Allowed || res.RetryAfter != 20*time.Second || res.Reset != time.Minute {
+		t.Fatalf("over limit: %+v", res)
+	}
+	if res, _ := s.Take(ctx, "other", limit, now); !res.Allowed {
+		t.Fatal("keys should not share buckets")
+	}
+	if res, _ := s.Take(ctx, "k", limit, now.Add(20*time.Second)); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refill: %+v", res)
+	}
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	// 每次登录都要做一次bcrypt比较，调低额度并拉长周期，避免测试期间补充令牌
+	prev := LoginRateLimit
+	LoginRateLimit = RateLimit{Requests: 3, Per: time.Hour}
+	t.Cleanup(func() { LoginRateLimit = prev })
+	r := newTestRouter(t)
+	attempts := 0
+	login := func(ip string) *httptest.ResponseRecorder {
+		// 每次使用不同的邮箱，避免先触发按账号的登录锁定
+		attempts++
+		body := fmt.Sprintf(`{"email":"user%d@example.com","password":"x"}`, attempts)
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := range LoginRateLimit.Requests {
+		w := login("192.0.2.1")
+		if w.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d limited too early", i)
+		}
+		if w.Header().Get("RateLimit-Limit") != "3" {
+			t.Fatalf("missing RateLimit headers: %v", w.Header())
+		}
+	}
+	w := login("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if retry, _ := strconv.Atoi(w.Header().Get("Retry-After")); w.Header().Get("RateLimit-Remaining") != "0" || retry <= 0 || retry > 1200 {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+	if w := login("192.0.2.2"); w.Code == http.StatusTooManyRequests {
+		t.Fatal("other IPs should not be limited")
+	}
+}
+
+func TestRegisterRateLimit(t *testing.T) {
+	r := newTestRouter(t)
+	register := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := range RegisterRateLimit.Requests {
+		w := register("192.0.2.1")
+		if w.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d limited too early", i)
+		}
+		if w.Header().Get("RateLimit-Limit") != "5" {
+			t.Fatalf("missing RateLimit headers: %v", w.Header())
+		}
+	}
+	w := register("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") != "720" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+	if w := register("192.0.2.2"); w.Code == http.StatusTooManyRequests {
+		t.Fatal("other IPs should not be limited")
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, RateLimit, time.Time) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("unavailable")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	r := newTestRouter(t)
+	rateLimitStore = failingRateLimitStore{}
+	for range RegisterRateLimit.Requests + 1 {
+		if w := doRequest(r, http.MethodPost, "/auth/register", "", nil); w.Code == http.StatusTooManyRequests {
+			t.Fatal("store errors should not block requests")
+		}
+	}
+}
+
+// 伪造的Redis服务，记录收到的命令并对EVAL返回固定结果
+func TestRedisRateLimitStore(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	commands := make(chan []string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		for {
+			v, err := readRESP(br)
+			if err != nil {
+				return
+			}
+			var args []string
+			for _, a := range v.([]any) {
+				args = append(args, a.(string))
+			}
+			commands <- args
+			switch args[0] {
+			case "AUTH":
+				conn.Write([]byte("+OK\r\n"))
+			case "EVAL":
+				conn.Write([]byte("*2\r\n:0\r\n$4\r\n0.25\r\n"))
+			default:
+				conn.Write([]byte("-ERR unknown command\r\n"))
+			}
+		}
+	}()
+
+	client, _ := NewRedisClient(RedisConfig{Addr: ln.Addr().String(), Password: "secret"})
+	defer client.Close()
+	store := NewRedisRateLimitStore(client)
+	limit := RateLimit{Requests: 10, Per: 10 * time.Second}
+	res, err := store.Take(context.Background(), "ratelimit:login:ip:1", limit, time.UnixMilli(5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed || res.Remaining != 0 || res.RetryAfter != 750*time.Millisecond {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if auth := <-commands; auth[0] != "AUTH" || auth[1] != "secret" {
+		t.Fatalf("expected AUTH first, got %v", auth)
+	}
+	eval := <-commands
+	if want := []string{"1", "ratelimit:login:ip:1", "10", "0.001", "5000"}; strings.Join(eval[2:], " ") != strings.Join(want, " ") {
+		t.Fatalf("EVAL args = %v", eval[2:])
+	}
+
+	if _, err := client.Do(context.Background(), "PING"); !errors.As(err, new(RedisError)) {
+		t.Fatalf("expected RedisError, got %v", err)
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	TrustedProxies = nil
+	r := newTestRouter(t)
+	register := func(forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for i := range RegisterRateLimit.Requests {
+		register(fmt.Sprintf("198.51.100.%d", i))
+	}
+	if w := register("198.51.100.200"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For bypassed the limit: %d", w.Code)
+	}
+
+	// 来自可信代理的请求按X-Forwarded-For中的客户端IP限流
+	TrustedProxies = []string{"192.0.2.0/24"}
+	t.Cleanup(func() { TrustedProxies = nil })
+	r = newTestRouter(t)
+	for range RegisterRateLimit.Requests {
+		register("198.51.100.1")
+	}
+	if w := register("198.51.100.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for the forwarded client, got %d", w.Code)
+	}
+	if w := register("198.51.100.2"); w.Code == http.StatusTooManyRequests {
+		t.Fatal("clients behind a trusted proxy should not share a bucket")
+	}
+}
@@ -0,0 +1,158 @@
+package blog
+
+import (
+	model "job/blog/Model"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 角色
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+	RoleReader = "reader"
+)
+
+// Permission 权限
+type Permission string
+
+const (
+	PermPostRead        Permission = "post:read"
+	PermPostCreate      Permission = "post:create"
+	PermPostModerate    Permission = "post:moderate" // 修改或删除任何人的文章
+	PermCommentRead     Permission = "comment:read"
+	PermCommentCreate   Permission = "comment:create"
+	PermCommentModerate Permission = "comment:moderate" // 删除任何人的评论
+	PermUserManage      Permission = "user:manage"      // 分配角色和权限
+)
+
+// 各角色拥有的权限
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermPostRead, PermPostCreate, PermPostModerate,
+		PermCommentRead, PermCommentCreate, PermCommentModerate,
+		PermUserManage,
+	},
+	RoleEditor: {
+		PermPostRead, PermPostCreate, PermPostModerate,
+		PermCommentRead, PermCommentCreate, PermCommentModerate,
+	},
+	RoleAuthor: {
+		PermPostRead, PermPostCreate,
+		PermCommentRead, PermCommentCreate,
+	},
+	RoleReader: {
+		PermPostRead,
+		PermCommentRead, PermCommentCreate,
+	},
+}
+
+// 权限配置
+var (
+	DefaultRole = RoleAuthor // 新注册用户的角色
+	AdminEmails []string     // 验证邮箱后自动成为管理员的邮箱
+
+	RequireEmailVerification = true // 邮箱验证之前是否禁止登录
+)
+
+// 角色和权限相关错误
+var (
+	ErrPermissionRequired = ForbiddenError("permission_required", "Permission is required") // 缺少访问路由所需的权限
+	ErrInvalidRole        = ValidationError("invalid_role", "Invalid role")
+	ErrInvalidPermission  = ValidationError("invalid_permission", "Invalid permission")
+)
+
+// InitRBAC 使用配置初始化默认角色、管理员邮箱和邮箱验证要求
+func InitRBAC(cfg AuthConfig) {
+	DefaultRole = cfg.DefaultRole
+	AdminEmails = cfg.AdminEmails
+	RequireEmailVerification = cfg.RequireEmailVerification
+}
+
+// IsValidRole 检查角色是否存在
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsValidPermission 检查权限是否存在
+func IsValidPermission(perm Permission) bool {
+	for _, perms := range rolePermissions {
+		if slices.Contains(perms, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// promoteVerifiedAdmin 邮箱验证之后，把使用AdminEmails中邮箱的用户提升为管理员
+// 注册时只分配DefaultRole，避免未证明拥有邮箱的用户获得管理员权限
+func promoteVerifiedAdmin(tx *gorm.DB, user *model.User) error {
+	if user.EmailVerifiedAt == nil || user.Role == RoleAdmin {
+		return nil
+	}
+	email := normalizeEmail(user.Email)
+	if !slices.ContainsFunc(AdminEmails, func(e string) bool { return normalizeEmail(e) == email }) {
+		return nil
+	}
+	user.Role = RoleAdmin
+	return tx.Model(user).Update("role", RoleAdmin).Error
+}
+
+// UserPermissions 用户的有效权限：角色权限加上额外授予的权限
+func UserPermissions(user *model.User) []string {
+	perms := []string{}
+	for _, p := range rolePermissions[user.Role] {
+		perms = append(perms, string(p))
+	}
+	for _, p := range strings.Split(user.Permissions, ",") {
+		if p = strings.TrimSpace(p); p != "" && !slices.Contains(perms, p) {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// HasPermission 检查当前用户是否拥有指定权限
+func HasPermission(c *gin.Context, perm Permission) bool {
+	claims, ok := GetCurrentUser(c)
+	if !ok {
+		return false
+	}
+	return slices.Contains(claims.Permissions, string(perm))
+}
+
+// RequirePermission 权限校验中间件，需要在AuthMiddleware之后使用，要求拥有全部指定权限
+// 使用个人访问Token时还要求拥有权限对应的授权范围
+func RequirePermission(perms ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetCurrentUser(c); !ok {
+			respondError(c, ErrUnauthorized)
+			return
+		}
+		for _, perm := range perms {
+			if !HasPermission(c, perm) {
+				respondError(c, ErrPermissionRequired.WithDetail("permission_required.permission", perm))
+				return
+			}
+			// 个人访问Token还需要有对应的授权范围
+			if scope := permissionScopes[perm]; !HasScope(c, scope) {
+				respondError(c, ErrScopeRequired.WithDetail("scope_required.scope", scope))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// canModify 资源所有者或拥有管理权限的用户可以修改资源
+func canModify(c *gin.Context, ownerID int, moderate Permission) bool {
+	if userID, ok := GetCurrentUserID(c); ok && userID == ownerID {
+		return true
+	}
+	return HasPermission(c, moderate)
+}
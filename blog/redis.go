@@ -0,0 +1,184 @@
+package blog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig Redis连接配置，也适用于兼容Redis协议的服务
+type RedisConfig struct {
+	Addr     string `yaml:"addr"` // 例如 localhost:6379
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	PoolSize int    `yaml:"pool_size"` // 最多保留的空闲连接数
+}
+
+// RedisError Redis返回的错误回复
+type RedisError string
+
+func (e RedisError) Error() string { return string(e) }
+
+// RedisClient 最小的RESP协议客户端，只用于执行少量命令，不依赖第三方库
+type RedisClient struct {
+	cfg    RedisConfig
+	idle   chan *redisConn
+	dialer net.Dialer
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedisClient 创建Redis客户端，连接在第一次使用时建立
+func NewRedisClient(cfg RedisConfig) (*RedisClient, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("redis addr is required")
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	return &RedisClient{
+		cfg:    cfg,
+		idle:   make(chan *redisConn, cfg.PoolSize),
+		dialer: net.Dialer{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// Do 执行一条命令并返回回复：string、int64、nil、[]any，错误回复返回RedisError
+func (r *RedisClient) Do(ctx context.Context, args ...string) (any, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(ctx, args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// 网络错误之后连接状态未知，直接丢弃
+		conn.Close()
+		return nil, err
+	}
+	r.put(conn)
+	return reply, err
+}
+
+// get 取一个空闲连接，没有时新建连接并完成认证和选库
+func (r *RedisClient) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+	nc, err := r.dialer.DialContext(ctx, "tcp", r.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	if r.cfg.Password != "" {
+		if _, err := conn.do(ctx, []string{"AUTH", r.cfg.Password}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if r.cfg.DB != 0 {
+		if _, err := conn.do(ctx, []string{"SELECT", strconv.Itoa(r.cfg.DB)}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (r *RedisClient) put(conn *redisConn) {
+	select {
+	case r.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// Close 关闭所有空闲连接
+func (r *RedisClient) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *redisConn) do(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	c.SetDeadline(deadline)
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRESP(c.r)
+}
+
+// readRESP 读取一个RESP2回复
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, RedisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			// 数组中的错误回复作为值返回，不中断读取
+			v, err := readRESP(r)
+			var redisErr RedisError
+			if errors.As(err, &redisErr) {
+				v = redisErr
+			} else if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
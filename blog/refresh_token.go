@@ -0,0 +1,202 @@
+package blog
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	model "job/blog/Model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidRefreshToken = UnauthorizedError("invalid_refresh_token", "Invalid refresh token")
+	ErrRefreshTokenExpired = UnauthorizedError("refresh_token_expired", "Refresh token expired")
+	ErrRefreshTokenReused  = UnauthorizedError("refresh_token_reused", "Refresh token reuse detected, session revoked")
+)
+
+// TokenPair 访问Token和刷新Token
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问Token有效期（秒）
+}
+
+// newRefreshTokenString 生成随机的刷新Token明文
+func newRefreshTokenString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashRefreshToken 数据库中只保存刷新Token的摘要
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokenPair 在指定家族中签发一对新的Token
+func issueTokenPair(tx *gorm.DB, user *model.User, familyID string, mfa bool) (*TokenPair, error) {
+	raw, err := newRefreshTokenString()
+	if err != nil {
+		return nil, err
+	}
+	rt := model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashRefreshToken(raw),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(RefreshTokenExpireDuration),
+		MFA:       mfa,
+	}
+	if err := tx.Create(&rt).Error; err != nil {
+		return nil, err
+	}
+
+	access, err := generateToken(user, familyID, mfa)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: raw,
+		ExpiresIn:    int64(TokenExpireDuration.Seconds()),
+	}, nil
+}
+
+// IssueTokenPair 登录成功后为用户创建新的Token家族
+func IssueTokenPair(user *model.User) (*TokenPair, error) {
+	return issueTokenPair(GetDB(), user, uuid.NewString(), false)
+}
+
+// IssueMFATokenPair 完成两步验证后创建新的Token家族，家族内的访问Token都带有mfa标记
+func IssueMFATokenPair(user *model.User) (*TokenPair, error) {
+	return issueTokenPair(GetDB(), user, uuid.NewString(), true)
+}
+
+// RotateRefreshToken 使用刷新Token换取新的Token对，旧的刷新Token立即失效
+// 已失效的刷新Token被再次使用时，认为Token已泄露，吊销整个家族
+func RotateRefreshToken(raw string) (*TokenPair, error) {
+	var rt model.RefreshToken
+	if err := GetDB().Where("token_hash = ?", hashRefreshToken(raw)).First(&rt).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if rt.UsedAt != nil || rt.RevokedAt != nil {
+		if err := RevokeFamily(rt.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	var pair *TokenPair
+	reused := false
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发刷新时只有一个请求能成功
+		res := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", rt.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			reused = true
+			return nil
+		}
+
+		var user model.User
+		if err := tx.First(&user, rt.UserID).Error; err != nil {
+			return err
+		}
+		var err error
+		pair, err = issueTokenPair(tx, &user, rt.FamilyID, rt.MFA)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		if err := RevokeFamily(rt.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// RevokeFamily 吊销整个Token家族：家族内的刷新Token和已签发的访问Token全部失效
+func RevokeFamily(familyID string) error {
+	return GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Model(&model.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+		return revoke(tx, familyID, model.RevokedKindFamily, now.Add(TokenExpireDuration))
+	})
+}
+
+// RevokeUserSessions 吊销用户的全部会话和个人访问Token，用于修改或重置密码之后
+func RevokeUserSessions(userID uint) error {
+	var families []string
+	err := GetDB().Model(&model.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Distinct().Pluck("family_id", &families).Error
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err := RevokeFamily(family); err != nil {
+			return err
+		}
+	}
+	return GetDB().Model(&model.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAccessToken 将访问Token的jti加入吊销列表
+func RevokeAccessToken(claims *CustomClaims) error {
+	if claims.ID == "" {
+		return nil
+	}
+	expiresAt := time.Now().Add(TokenExpireDuration)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return revoke(GetDB(), claims.ID, model.RevokedKindAccess, expiresAt)
+}
+
+// revoke 写入吊销列表，重复吊销时忽略
+func revoke(tx *gorm.DB, jti, kind string, expiresAt time.Time) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.RevokedToken{JTI: jti, Kind: kind, ExpiresAt: expiresAt}).Error
+}
+
+// IsTokenRevoked 检查访问Token本身或其所属家族是否已被吊销
+func IsTokenRevoked(claims *CustomClaims) (bool, error) {
+	jtis := []string{claims.ID}
+	if claims.FamilyID != "" {
+		jtis = append(jtis, claims.FamilyID)
+	}
+	var count int64
+	err := GetDB().Model(&model.RevokedToken{}).Where("jti IN ?", jtis).Count(&count).Error
+	return count > 0, err
+}
+
+// PurgeExpiredTokens 清理已经过期的刷新Token和吊销记录
+func PurgeExpiredTokens() error {
+	now := time.Now()
+	if err := GetDB().Unscoped().Where("expires_at < ?", now).Delete(&model.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	return GetDB().Unscoped().Where("expires_at < ?", now).Delete(&model.RevokedToken{}).Error
+}
@@ -0,0 +1,105 @@
+package blog
+
+import (
+	"errors"
+	model "job/blog/Model"
+	"testing"
+	"time"
+)
+
+func createTestUser(t *testing.T, username string) *model.User {
+	t.Helper()
+	user := model.User{Username: username, Email: username + "@example.com", Password: "x"}
+	if err := GetDB().Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return &user
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "alice")
+
+	first, err := IssueTokenPair(user)
+	if err != nil {
+		t.Fatalf("IssueTokenPair: %v", err)
+	}
+	second, err := RotateRefreshToken(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	// 重放旧的刷新Token会吊销整个家族
+	if _, err := RotateRefreshToken(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := RotateRefreshToken(second.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("after family revocation err = %v, want ErrRefreshTokenReused", err)
+	}
+
+	claims, err := ParseToken(second.AccessToken)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if revoked, _ := IsTokenRevoked(claims); !revoked {
+		t.Fatal("access token of revoked family should be rejected")
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	setupTestDB(t)
+	user := createTestUser(t, "bob")
+
+	pair, err := IssueTokenPair(user)
+	if err != nil {
+		t.Fatalf("IssueTokenPair: %v", err)
+	}
+	claims, err := ParseToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if revoked, _ := IsTokenRevoked(claims); revoked {
+		t.Fatal("fresh token should not be revoked")
+	}
+	if err := RevokeAccessToken(claims); err != nil {
+		t.Fatalf("RevokeAccessToken: %v", err)
+	}
+	if revoked, _ := IsTokenRevoked(claims); !revoked {
+		t.Fatal("token should be revoked")
+	}
+	if err := RevokeAccessToken(claims); err != nil {
+		t.Fatalf("revoking twice: %v", err)
+	}
+}
+
+func TestSchedulerPurgesExpiredTokens(t *testing.T) {
+	setupTestDB(t)
+	expired := time.Now().Add(-time.Minute)
+	GetDB().Create(&model.RevokedToken{JTI: "old", Kind: model.RevokedKindAccess, ExpiresAt: expired})
+	GetDB().Create(&model.RevokedToken{JTI: "new", Kind: model.RevokedKindAccess, ExpiresAt: time.Now().Add(time.Hour)})
+
+	StartScheduler(10 * time.Millisecond)
+	t.Cleanup(StopScheduler)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var jtis []string
+		GetDB().Model(&model.RevokedToken{}).Order("jti").Pluck("jti", &jtis)
+		if len(jtis) == 1 && jtis[0] == "new" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expired tokens not purged: %v", jtis)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRotateRefreshTokenInvalid(t *testing.T) {
+	setupTestDB(t)
+	if _, err := RotateRefreshToken("bogus"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
@@ -0,0 +1,191 @@
+package blog
+
+import (
+	"errors"
+	model "job/blog/Model"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 版本列表支持的排序字段
+var revisionSorts = []string{"created_at", "id"}
+
+// ErrRevisionNotFound 文章没有对应版本号的版本
+var ErrRevisionNotFound = NotFoundError("revision_not_found", "Revision not found")
+
+// lockPost 在事务中加锁读取文章的最新内容，修改已有文章时在它的基础上应用改动
+// 同一篇文章的修改因此依次进行，不会互相覆盖字段，版本号也依次分配
+func lockPost(tx *gorm.DB, id uint) (*model.Post, error) {
+	var post model.Post
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tags").Preload("Categories").First(&post, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// savePost 在事务中渲染并保存文章，标题或内容发生变化时记录一个新版本
+// 没有版本记录的旧文章会先以数据库中的内容补一个初始版本
+// 修改已有文章时post必须是同一事务中通过lockPost读取的
+func savePost(tx *gorm.DB, post *model.Post, editorID int, restoredFrom *int) error {
+	var latest model.PostRevision
+	hasLatest := false
+	if post.ID != 0 {
+		err := tx.Where("post_id = ?", post.ID).Order("number DESC").First(&latest).Error
+		switch {
+		case err == nil:
+			hasLatest = true
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			var orig model.Post
+			if err := tx.First(&orig, post.ID).Error; err != nil {
+				return err
+			}
+			latest = model.PostRevision{PostID: orig.ID, Number: 1, UserID: orig.UserID, Title: orig.Title, Content: orig.Content}
+			latest.CreatedAt = orig.UpdatedAt
+			if err := tx.Create(&latest).Error; err != nil {
+				return err
+			}
+			hasLatest = true
+		default:
+			return err
+		}
+	}
+
+	post.ContentHTML = RenderMarkdown(post.Content)
+	// 标签和分类通过setPostTaxonomy单独维护，计数列只做原子更新
+	if err := tx.Omit(append([]string{clause.Associations}, postCounterColumns...)...).Save(post).Error; err != nil {
+		return err
+	}
+	if hasLatest && restoredFrom == nil && latest.Title == post.Title && latest.Content == post.Content {
+		return nil
+	}
+	return tx.Create(&model.PostRevision{
+		PostID:       post.ID,
+		Number:       latest.Number + 1,
+		UserID:       editorID,
+		Title:        post.Title,
+		Content:      post.Content,
+		RestoredFrom: restoredFrom,
+	}).Error
+}
+
+// findEditablePost 查找当前用户可以修改的文章，版本历史只对作者和审核人员开放
+func findEditablePost(c *gin.Context) (*model.Post, bool) {
+	post, ok := findPost(c)
+	if !ok {
+		return nil, false
+	}
+	if !canModify(c, post.UserID, PermPostModerate) {
+		respondError(c, ErrNotPostOwner)
+		return nil, false
+	}
+	return post, true
+}
+
+// findRevision 按版本号查找文章的版本，不存在时返回404
+func findRevision(c *gin.Context, postID uint, number string) (*model.PostRevision, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		respondError(c, ErrInvalidID.WithDetail("invalid_id.revision"))
+		return nil, false
+	}
+	var rev model.PostRevision
+	if err := GetDB().Where("post_id = ? AND number = ?", postID, n).First(&rev).Error; err != nil {
+		respondError(c, ErrRevisionNotFound)
+		return nil, false
+	}
+	return &rev, true
+}
+
+// listRevisions GET /api/posts/:id/revisions
+func listRevisions(c *gin.Context) {
+	post, ok := findEditablePost(c)
+	if !ok {
+		return
+	}
+	q := GetDB().Model(&model.PostRevision{}).Where("post_id = ?", post.ID)
+	res, err := paginate(c, q, revisionSorts, "-created_at", func(r *model.PostRevision) (time.Time, uint) {
+		return r.CreatedAt, r.ID
+	})
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	respondPage(c, res.Items, res.Pagination, res.Links)
+}
+
+// showRevision GET /api/posts/:id/revisions/:rev
+func showRevision(c *gin.Context) {
+	post, ok := findEditablePost(c)
+	if !ok {
+		return
+	}
+	rev, ok := findRevision(c, post.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+	respondData(c, http.StatusOK, rev)
+}
+
+// diffRevisions GET /api/posts/:id/revisions/diff?from=1&to=2
+// 返回两个版本标题和内容的行级差异
+func diffRevisions(c *gin.Context) {
+	post, ok := findEditablePost(c)
+	if !ok {
+		return
+	}
+	if c.Query("from") == "" || c.Query("to") == "" {
+		respondError(c, invalidQuery("invalid_query.revision_range"))
+		return
+	}
+	from, ok := findRevision(c, post.ID, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := findRevision(c, post.ID, c.Query("to"))
+	if !ok {
+		return
+	}
+	respondData(c, http.StatusOK, gin.H{
+		"from":    from.Number,
+		"to":      to.Number,
+		"title":   DiffLines(from.Title, to.Title),
+		"content": DiffLines(from.Content, to.Content),
+	})
+}
+
+// rollbackRevision POST /api/posts/:id/revisions/:rev/rollback
+// 把旧版本的内容恢复为文章的最新版本，历史版本保持不变
+func rollbackRevision(c *gin.Context) {
+	post, ok := findEditablePost(c)
+	if !ok {
+		return
+	}
+	rev, ok := findRevision(c, post.ID, c.Param("rev"))
+	if !ok {
+		return
+	}
+	userID, _ := GetCurrentUserID(c)
+	err := inTransaction(func(tx *gorm.DB) error {
+		fresh, err := lockPost(tx, post.ID)
+		if err != nil {
+			return err
+		}
+		fresh.Title, fresh.Content = rev.Title, rev.Content
+		if err := savePost(tx, fresh, userID, &rev.Number); err != nil {
+			return err
+		}
+		post = fresh
+		return nil
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to restore revision", err))
+		return
+	}
+	respondData(c, http.StatusOK, post)
+}
@@ -0,0 +1,141 @@
+package blog
+
+import (
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiffLines(t *testing.T) {
+	got := DiffLines("a\nb\nc\nd", "a\nc\nx\nd")
+	want := []DiffLine{
+		{Op: DiffEqual, OldLine: 1, NewLine: 1, Text: "a"},
+		{Op: DiffDelete, OldLine: 2, Text: "b"},
+		{Op: DiffEqual, OldLine: 3, NewLine: 2, Text: "c"},
+		{Op: DiffInsert, NewLine: 3, Text: "x"},
+		{Op: DiffEqual, OldLine: 4, NewLine: 4, Text: "d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DiffLines = %+v", got)
+	}
+	if got := DiffLines("", "new"); len(got) != 1 || got[0].Op != DiffInsert {
+		t.Fatalf("DiffLines from empty = %+v", got)
+	}
+
+	// 超过maxDiffCells时不再计算最长公共子序列，整体删除再整体插入
+	var from, to []string
+	for i := range 600 {
+		from = append(from, fmt.Sprintf("old %d", i))
+		to = append(to, fmt.Sprintf("new %d", i))
+	}
+	from[300], to[300] = "same", "same"
+	got = DiffLines(strings.Join(from, "\n"), strings.Join(to, "\n"))
+	if len(got) != 1200 || got[599].Op != DiffDelete || got[600].Op != DiffInsert {
+		t.Fatalf("large diff has %d lines, want whole replacement", len(got))
+	}
+}
+
+func TestRevisionHistoryAndRollback(t *testing.T) {
+	r := newTestRouter(t)
+	author, token := loginAs(t, "author", RoleAuthor)
+	_, otherToken := loginAs(t, "other", RoleAuthor)
+	_, editorToken := loginAs(t, "editor", RoleEditor)
+
+	// 没有版本记录的旧文章，第一次修改时补上初始版本
+	post := model.Post{Title: "v1", Content: "line1\nline2", UserID: int(author.ID)}
+	GetDB().Create(&post)
+	base := fmt.Sprintf("/api/posts/%d", post.ID)
+
+	if w := doRequest(r, http.MethodPatch, base, editorToken, gin.H{"content": "line1\nline2 edited"}); w.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", w.Code, w.Body)
+	}
+	// 只修改状态不产生新版本
+	if w := doRequest(r, http.MethodPatch, base, token, gin.H{"status": model.PostStatusArchived}); w.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", w.Code, w.Body)
+	}
+
+	var revs []model.PostRevision
+	GetDB().Where("post_id = ?", post.ID).Order("number").Find(&revs)
+	if len(revs) != 2 || revs[0].UserID != int(author.ID) || revs[1].Content != "line1\nline2 edited" {
+		t.Fatalf("revisions = %+v", revs)
+	}
+
+	if w := doRequest(r, http.MethodGet, base+"/revisions", otherToken, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("other list status = %d, want 404 for archived post", w.Code)
+	}
+
+	w := doRequest(r, http.MethodGet, base+"/revisions/diff?from=1&to=2", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("diff status = %d: %s", w.Code, w.Body)
+	}
+	var diff struct{ Content []DiffLine }
+	decodeData(w, &diff)
+	if len(diff.Content) != 3 || diff.Content[1].Op != DiffDelete || diff.Content[2].Text != "line2 edited" {
+		t.Fatalf("diff = %+v", diff.Content)
+	}
+
+	if w := doRequest(r, http.MethodPost, base+"/revisions/1/rollback", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("rollback status = %d: %s", w.Code, w.Body)
+	}
+	var restored model.Post
+	GetDB().First(&restored, post.ID)
+	var latest model.PostRevision
+	GetDB().Where("post_id = ?", post.ID).Order("number DESC").First(&latest)
+	if restored.Content != "line1\nline2" || latest.Number != 3 || latest.RestoredFrom == nil || *latest.RestoredFrom != 1 {
+		t.Fatalf("after rollback post = %+v, latest = %+v", restored, latest)
+	}
+
+	if w := doRequest(r, http.MethodGet, base+"/revisions/9", token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing revision status = %d, want 404", w.Code)
+	}
+}
+
+func TestConcurrentEditsKeepEachOthersChanges(t *testing.T) {
+	r := newTestRouter(t)
+	author, token := loginAs(t, "author", RoleAuthor)
+	post := model.Post{Title: "v1", Content: "start", UserID: int(author.ID)}
+	GetDB().Create(&post)
+	base := fmt.Sprintf("/api/posts/%d", post.ID)
+
+	const edits = 5
+	codes := make(chan int, edits)
+	var wg sync.WaitGroup
+	for i := 0; i < edits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 一半请求只改标题，一半只改内容，互相不能覆盖
+			body := gin.H{"content": fmt.Sprintf("edit %d", i)}
+			if i%2 == 0 {
+				body = gin.H{"title": fmt.Sprintf("title %d", i)}
+			}
+			codes <- doRequest(r, http.MethodPatch, base, token, body).Code
+		}(i)
+	}
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("concurrent patch status = %d", code)
+		}
+	}
+
+	var numbers []int
+	GetDB().Model(&model.PostRevision{}).Where("post_id = ?", post.ID).Order("number").Pluck("number", &numbers)
+	if len(numbers) != edits+1 || numbers[0] != 1 || numbers[edits] != edits+1 {
+		t.Fatalf("revision numbers = %v", numbers)
+	}
+	var stored model.Post
+	GetDB().First(&stored, post.ID)
+	var latest model.PostRevision
+	GetDB().Where("post_id = ?", post.ID).Order("number DESC").First(&latest)
+	if stored.Title == "v1" || stored.Content == "start" || latest.Title != stored.Title || latest.Content != stored.Content {
+		t.Fatalf("post = %q/%q, latest revision = %q/%q", stored.Title, stored.Content, latest.Title, latest.Content)
+	}
+}
@@ -1,12 +1,16 @@
 package blog
 
 import (
+	"errors"
 	model "job/blog/Model"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // 登录请求结构
@@ -22,30 +26,47 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+// 刷新Token请求结构
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// 设置用户角色请求结构
+type SetUserRoleRequest struct {
+	UserID      uint     `json:"user_id" binding:"required"`
+	Role        string   `json:"role" binding:"required"`
+	Permissions []string `json:"permissions"`
+}
+
 // 登录响应结构
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  any    `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	User         any    `json:"user"`
 }
 
+// ErrUserExists 注册的邮箱已被使用
+var ErrUserExists = ConflictError("user_exists", "User already exists")
+
 // register 用户注册
 func register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, BindError(err))
 		return
 	}
 
 	// 检查用户是否已存在
 	if err := GetDB().Where("email = ?", req.Email).First(&model.User{}).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User already exists"})
+		respondError(c, ErrUserExists)
 		return
 	}
 
 	// 加密密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		respondError(c, InternalError("Failed to hash password", err))
 		return
 	}
 
@@ -53,18 +74,36 @@ func register(c *gin.Context) {
 		Username: req.Username,
 		Email:    req.Email,
 		Password: string(hashedPassword),
+		Role:     DefaultRole,
 	}
 
-	GetDB().Create(&user)
-	token, err := GenerateToken(user.ID, user.Username, user.Email)
+	// 用户名重复或并发注册同一邮箱时由唯一索引拒绝
+	if err := GetDB().Create(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondError(c, ErrUserExists)
+			return
+		}
+		respondError(c, InternalError("Failed to create user", err))
+		return
+	}
+	sendVerificationEmail(c, user)
+	// 需要验证邮箱时，验证之后才能登录
+	if RequireEmailVerification {
+		c.JSON(http.StatusCreated, gin.H{"user": user, "message": T(c, "verification_email_sent")})
+		return
+	}
+
+	pair, err := IssueTokenPair(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		respondError(c, InternalError("Failed to generate token", err))
 		return
 	}
 
 	c.JSON(http.StatusCreated, LoginResponse{
-		Token: token,
-		User:  user,
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		User:         user,
 	})
 }
 
@@ -72,77 +111,157 @@ func register(c *gin.Context) {
 func login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, BindError(err))
+		return
+	}
+
+	// 先检查失败次数，锁定期间不再验证密码
+	email := normalizeEmail(req.Email)
+	wait, err := loginBlockedFor(email, c.ClientIP(), time.Now())
+	if err != nil {
+		respondError(c, InternalError("Login failed", err))
+		return
+	}
+	if wait > 0 {
+		recordLoginAttempt(c, nil, email, false, LoginReasonLocked)
+		abortLoginLocked(c, wait)
 		return
 	}
 
+	// 邮箱不存在和密码错误返回同样的响应
 	var user model.User
 	if err := GetDB().Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		compareDummyPassword(req.Password)
+		recordLoginAttempt(c, nil, email, false, LoginReasonInvalidCredentials)
+		respondError(c, ErrInvalidCredentials)
 		return
 	}
-
-	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		recordLoginAttempt(c, &user.ID, email, false, LoginReasonInvalidCredentials)
+		respondError(c, ErrInvalidCredentials)
+		return
+	}
+	if RequireEmailVerification && user.EmailVerifiedAt == nil {
+		recordLoginAttempt(c, &user.ID, email, false, LoginReasonUnverified)
+		respondError(c, ErrEmailNotVerified)
+		return
+	}
+	// 启用了两步验证时，登录在 /auth/login/2fa 中完成
+	if user.TOTPEnabledAt != nil {
+		startTwoFactorLogin(c, &user)
 		return
 	}
+	recordLoginAttempt(c, &user.ID, email, true, "")
 
 	// 生成Token
-	token, err := GenerateToken(user.ID, user.Username, user.Email)
+	pair, err := IssueTokenPair(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成Token失败"})
+		respondError(c, InternalError("Failed to generate token", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		User:  user,
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		User:         user,
 	})
 }
 
-// refreshToken 刷新Token
+// refreshToken 使用刷新Token换取新的Token对
 func refreshToken(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization header is required"})
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
 		return
 	}
 
-	tokenString := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		tokenString = authHeader[7:]
+	pair, err := RotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		respondError(c, classifyError(err, "Failed to refresh token"))
+		return
 	}
 
-	newToken, err := RefreshToken(tokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, pair)
+}
+
+// logout 登出：吊销当前访问Token及其所属的刷新Token家族
+func logout(c *gin.Context) {
+	claims, exists := GetCurrentUser(c)
+	if !exists {
+		respondError(c, ErrUnauthorized)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": newToken})
+	if err := RevokeAccessToken(claims); err != nil {
+		respondError(c, InternalError("Failed to revoke token", err))
+		return
+	}
+	if claims.FamilyID != "" {
+		if err := RevokeFamily(claims.FamilyID); err != nil {
+			respondError(c, InternalError("Failed to revoke token", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": T(c, "logged_out")})
 }
 
 // profile 获取用户信息（需要认证）
 func profile(c *gin.Context) {
 	user, exists := GetCurrentUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		respondError(c, ErrUnauthorized)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"user_id":  user.UserID,
-		"username": user.Username,
-		"email":    user.Email,
+	respondData(c, http.StatusOK, gin.H{
+		"user_id":     user.UserID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"role":        user.Role,
+		"permissions": user.Permissions,
+		"locale":      userLocale(c),
 	})
 }
 
+// setUserRole 设置用户角色和额外权限（需要user:manage权限），新Token签发后生效
+func setUserRole(c *gin.Context) {
+	var req SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	if !IsValidRole(req.Role) {
+		respondError(c, ErrInvalidRole)
+		return
+	}
+	for _, p := range req.Permissions {
+		if !IsValidPermission(Permission(p)) {
+			respondError(c, ErrInvalidPermission.WithDetail("invalid_permission.permission", p))
+			return
+		}
+	}
+
+	var user model.User
+	if err := GetDB().First(&user, req.UserID).Error; err != nil {
+		respondError(c, ErrUserNotFound)
+		return
+	}
+	user.Role = req.Role
+	user.Permissions = strings.Join(req.Permissions, ",")
+	if err := GetDB().Save(&user).Error; err != nil {
+		respondError(c, InternalError("Failed to update user", err))
+		return
+	}
+	c.JSON(http.StatusOK, Envelope{Data: user, Message: T(c, "user_role_updated")})
+}
+
 // 创建文章
 func createPost(c *gin.Context) {
 	var post model.Post
 	if err := c.ShouldBindJSON(&post); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, BindError(err))
 		return
 	}
 
@@ -150,10 +269,20 @@ func createPost(c *gin.Context) {
 	if userID, exists := GetCurrentUserID(c); exists {
 		post.UserID = userID
 	}
+	status, publishAt := post.Status, post.PublishAt
+	post.Status, post.PublishAt, post.PublishedAt = "", nil, nil
+	post.Tags, post.Categories = nil, nil
+	if err := setPostStatus(&post, status, publishAt, time.Now()); err != nil {
+		respondError(c, err)
+		return
+	}
 
 	// 保存到数据库
-	if err := GetDB().Create(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+	err := inTransaction(func(tx *gorm.DB) error {
+		return savePost(tx, &post, post.UserID, nil)
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to create post", err))
 		return
 	}
 
@@ -162,26 +291,44 @@ func createPost(c *gin.Context) {
 
 // 获取文章详情
 func getPost(c *gin.Context) {
+	// format=html 时返回渲染后的HTML，默认返回Markdown原文
+	format, err := contentFormat(c)
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
 	postID := c.Query("id")
 	if postID != "" {
 		// 获取特定文章
 		id, err := strconv.Atoi(postID)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+			respondError(c, ErrInvalidID)
 			return
 		}
 
-		var post model.Post
-		if err := GetDB().Where("id = ?", id).First(&post).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		// 未发布的文章只对作者和审核人员可见
+		post, err := visiblePost(c, id)
+		if err != nil {
+			respondError(c, ErrPostNotFound)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "Post details", "post": []model.Post{post}})
+		posts := []model.Post{*post}
+		formatPosts(format, posts)
+		c.JSON(http.StatusOK, gin.H{"message": T(c, "post_details"), "post": posts})
 	} else {
-		// 获取所有文章
-		var posts []model.Post
-		GetDB().Find(&posts)
-		c.JSON(http.StatusOK, gin.H{"message": "Post details", "post": posts})
+		// 分页获取文章列表
+		res, err := queryPosts(c)
+		if err != nil {
+			abortQuery(c, err)
+			return
+		}
+		formatPosts(format, res.Items)
+		c.JSON(http.StatusOK, gin.H{
+			"message":    T(c, "post_details"),
+			"post":       res.Items,
+			"pagination": res.Pagination,
+			"links":      res.Links,
+		})
 	}
 }
 
@@ -190,24 +337,37 @@ func updatePost(c *gin.Context) {
 	postID := c.Query("id")
 	id, err := strconv.Atoi(postID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, ErrInvalidID)
 		return
 	}
-	userID, exists := GetCurrentUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	var post model.Post
+	if err := GetDB().Where("id = ?", id).First(&post).Error; err != nil {
+		respondError(c, ErrPostNotFound)
 		return
 	}
-	var post model.Post
-	GetDB().Where("id = ?", id).First(&post)
-	if post.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this post"})
+	if !canModify(c, post.UserID, PermPostModerate) {
+		respondError(c, ErrNotPostOwner)
 		return
 	}
-	post.Title = c.Query("title")
-	post.Content = c.Query("content")
-	GetDB().Save(&post)
-	c.JSON(http.StatusOK, gin.H{"message": "Post updated", "post": post})
+	userID, _ := GetCurrentUserID(c)
+	err = inTransaction(func(tx *gorm.DB) error {
+		fresh, err := lockPost(tx, post.ID)
+		if err != nil {
+			return err
+		}
+		fresh.Title = c.Query("title")
+		fresh.Content = c.Query("content")
+		if err := savePost(tx, fresh, userID, nil); err != nil {
+			return err
+		}
+		post = *fresh
+		return nil
+	})
+	if err != nil {
+		respondError(c, classifyError(err, "Failed to update post"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": T(c, "post_updated"), "post": post})
 }
 
 // 删除文章
@@ -215,38 +375,53 @@ func deletePost(c *gin.Context) {
 	postID := c.Query("id")
 	id, err := strconv.Atoi(postID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, ErrInvalidID)
 		return
 	}
-	userID, exists := GetCurrentUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	var post model.Post
+	if err := GetDB().Where("id = ?", id).First(&post).Error; err != nil {
+		respondError(c, ErrPostNotFound)
 		return
 	}
-	var post model.Post
-	GetDB().Where("id = ?", id).First(&post)
-	if post.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this post"})
+	if !canModify(c, post.UserID, PermPostModerate) {
+		respondError(c, ErrNotPostOwner)
 		return
 	}
-	GetDB().Delete(&post)
-	c.JSON(http.StatusOK, gin.H{"message": "Post deleted"})
+	if err := removePost(c, &post); err != nil {
+		respondError(c, InternalError("Failed to delete post", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": T(c, "post_deleted")})
 }
 
 // 创建评论
 func createComment(c *gin.Context) {
 	var comment model.Comment
 	if err := c.ShouldBindJSON(&comment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, BindError(err))
 		return
 	}
 
 	if userID, exists := GetCurrentUserID(c); exists {
 		comment.UserID = userID
 	}
+	if _, err := visiblePost(c, comment.PostID); err != nil {
+		respondError(c, ErrPostNotFound)
+		return
+	}
+	if err := attachParent(&comment); err != nil {
+		respondError(c, err)
+		return
+	}
 
-	GetDB().Create(&comment)
-	c.JSON(http.StatusCreated, gin.H{"message": "Comment created", "code": 0})
+	err := inTransaction(func(tx *gorm.DB) error {
+		return addComment(tx, &comment)
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to create comment", err))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": T(c, "comment_created"), "code": 0})
 }
 
 // 获取评论
@@ -254,35 +429,158 @@ func getComment(c *gin.Context) {
 	postID := c.Query("id")
 	id, err := strconv.Atoi(postID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, ErrInvalidID)
+		return
+	}
+	if _, err := visiblePost(c, id); err != nil {
+		respondError(c, ErrPostNotFound)
 		return
 	}
 
-	var comments []model.Comment
-	GetDB().Where("post_id = ?", id).Find(&comments)
-	c.JSON(http.StatusOK, gin.H{"message": "Comment details", "code": 0, "data": comments})
+	res, err := queryComments(c, id)
+	if err != nil {
+		abortQuery(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    T(c, "comment_details"),
+		"code":       0,
+		"data":       res.Items,
+		"pagination": res.Pagination,
+		"links":      res.Links,
+	})
 }
 
+// 删除评论
+func deleteComment(c *gin.Context) {
+	commentID := c.Query("id")
+	id, err := strconv.Atoi(commentID)
+	if err != nil {
+		respondError(c, ErrInvalidID)
+		return
+	}
+	var comment model.Comment
+	if err := GetDB().Where("id = ?", id).First(&comment).Error; err != nil {
+		respondError(c, ErrCommentNotFound)
+		return
+	}
+	if !canModify(c, comment.UserID, PermCommentModerate) {
+		respondError(c, ErrNotCommentOwner)
+		return
+	}
+	err = inTransaction(func(tx *gorm.DB) error {
+		return removeComment(tx, &comment)
+	})
+	if err != nil {
+		respondError(c, InternalError("Failed to delete comment", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": T(c, "comment_deleted")})
+}
+
+// LegacyRoutes 是否注册旧的RPC风格路由（/api/create_post 等）
+var LegacyRoutes = true
+
+// TrustedProxies 可信代理的IP或CIDR，只有来自这些地址的请求才使用X-Forwarded-For确定客户端IP
+// 默认不信任任何代理，客户端IP取连接的对端地址，避免伪造请求头绕过按IP的限流和登录锁定
+var TrustedProxies []string
+
 func RegisterRoutes(r *gin.Engine) {
+	// 请求ID、请求指标、访问日志和panic恢复，错误统一以problem+json返回
+	registerJSONFieldNames()
+	registerValidatorTranslations()
+	if err := r.SetTrustedProxies(TrustedProxies); err != nil {
+		logger.Error("Invalid trusted proxies, trusting none", "error", err)
+		r.SetTrustedProxies(nil)
+	}
+	r.Use(RequestID(), Metrics(), AccessLog(), Recovery(), ErrorHandler())
+	r.NoRoute(noRoute)
+
+	// 公钥集合，供其他服务验证Token
+	r.GET("/.well-known/jwks.json", jwks)
+
 	// 公开路由（不需要认证）
 	auth := r.Group("/auth")
 	{
-		auth.POST("/register", register)
-		auth.POST("/login", login)
+		auth.POST("/register", RateLimitMiddleware("register", RegisterRateLimit, KeyByIP), register)
+		auth.POST("/login", RateLimitMiddleware("login", LoginRateLimit, KeyByIP), login)
+		auth.POST("/login/2fa", RateLimitMiddleware("login-2fa", LoginRateLimit, KeyByIP), loginTwoFactor)
 		auth.POST("/refresh", refreshToken)
+		auth.POST("/logout", AuthMiddleware(), RequireSession(), logout)
+		auth.GET("/verify", verifyEmail)
+		auth.POST("/verify", verifyEmail)
+		auth.POST("/verify/resend", RateLimitMiddleware("resend_verification", ForgotPasswordRateLimit, KeyByIP), resendVerification)
+		auth.POST("/forgot", RateLimitMiddleware("forgot", ForgotPasswordRateLimit, KeyByIP), forgotPassword)
+		auth.POST("/reset", resetPassword)
+		auth.GET("/oidc", listOIDCProviders)
+		auth.GET("/oidc/:provider", oidcLogin)
+		auth.GET("/oidc/:provider/callback", RateLimitMiddleware("oidc", LoginRateLimit, KeyByIP), oidcCallback)
 	}
 
 	// 需要认证的路由
 	protected := r.Group("/api")
 	protected.Use(AuthMiddleware())
 	{
-		protected.GET("/profile", profile)
-		protected.POST("/create_post", createPost)
-		protected.GET("/get_post", getPost)
-		protected.POST("/update_post", updatePost)
-		protected.POST("/delete_post", deletePost)
-		protected.POST("/create_comment", createComment)
-		protected.GET("/get_comment", getComment)
+		protected.GET("/profile", RequireScope(ScopeProfileRead), profile)
+		protected.GET("/profile/sessions", RequireScope(ScopeProfileRead), loginSessions)
+		protected.GET("/profile/identities", RequireScope(ScopeProfileRead), listIdentities)
+		protected.PUT("/profile/locale", RequireSession(), setLocale)
+		protected.GET("/profile/2fa", RequireSession(), twoFactorStatus)
+		protected.POST("/profile/2fa", RequireSession(), enrollTwoFactor)
+		protected.POST("/profile/2fa/confirm", RequireSession(), confirmTwoFactor)
+		protected.DELETE("/profile/2fa", RequireSession(), RequireMFA(), disableTwoFactor)
+		protected.POST("/profile/2fa/recovery-codes", RequireSession(), RequireMFA(), regenerateRecoveryCodes)
+		protected.GET("/tokens", RequireSession(), listPATs)
+		protected.POST("/tokens", RequireSession(), createPAT)
+		protected.DELETE("/tokens/:id", RequireSession(), revokePAT)
+		protected.POST("/set_user_role", RequirePermission(PermUserManage), setUserRole)
+
+		protected.GET("/posts", RequirePermission(PermPostRead), listPosts)
+		protected.POST("/posts", RequirePermission(PermPostCreate), storePost)
+		protected.GET("/posts/:id", RequirePermission(PermPostRead), showPost)
+		protected.PATCH("/posts/:id", RequireScope(ScopePostsWrite), patchPost)
+		protected.DELETE("/posts/:id", RequireScope(ScopePostsWrite), destroyPost)
+		protected.GET("/posts/:id/revisions", RequireScope(ScopePostsRead), listRevisions)
+		protected.GET("/posts/:id/revisions/diff", RequireScope(ScopePostsRead), diffRevisions)
+		protected.GET("/posts/:id/revisions/:rev", RequireScope(ScopePostsRead), showRevision)
+		protected.POST("/posts/:id/revisions/:rev/rollback", RequireScope(ScopePostsWrite), rollbackRevision)
+		protected.GET("/posts/:id/comments", RequirePermission(PermCommentRead), listPostComments)
+		protected.GET("/posts/:id/comments/tree", RequirePermission(PermCommentRead), commentTree)
+		protected.POST("/posts/:id/comments", RequirePermission(PermCommentCreate), RateLimitMiddleware("comment", CommentRateLimit, KeyByUser), storePostComment)
+		protected.GET("/posts/:id/attachments", RequirePermission(PermPostRead), listAttachments)
+		protected.POST("/posts/:id/attachments", RequirePermission(PermPostCreate), uploadAttachment)
+		protected.GET("/attachments/:id", RequirePermission(PermPostRead), downloadAttachment)
+		protected.GET("/attachments/:id/thumbnail", RequirePermission(PermPostRead), attachmentThumbnail)
+		protected.DELETE("/attachments/:id", RequireScope(ScopePostsWrite), destroyAttachment)
+		protected.PUT("/posts/:id/like", RequirePermission(PermPostRead), RequireScope(ScopePostsWrite), likePost)
+		protected.DELETE("/posts/:id/like", RequirePermission(PermPostRead), RequireScope(ScopePostsWrite), unlikePost)
+		protected.PUT("/posts/:id/bookmark", RequirePermission(PermPostRead), RequireScope(ScopePostsWrite), bookmarkPost)
+		protected.DELETE("/posts/:id/bookmark", RequirePermission(PermPostRead), RequireScope(ScopePostsWrite), unbookmarkPost)
+		protected.GET("/bookmarks", RequirePermission(PermPostRead), listBookmarks)
+		protected.GET("/comments/:id/reactions", RequirePermission(PermCommentRead), listCommentReactions)
+		protected.PUT("/comments/:id/reactions/:reaction", RequirePermission(PermCommentRead), RequireScope(ScopeCommentsWrite), reactComment)
+		protected.DELETE("/comments/:id/reactions/:reaction", RequirePermission(PermCommentRead), RequireScope(ScopeCommentsWrite), unreactComment)
+		protected.DELETE("/comments/:id", RequireScope(ScopeCommentsWrite), destroyComment)
+
+		protected.GET("/tags", RequirePermission(PermPostRead), tagCloud)
+		protected.GET("/categories", RequirePermission(PermPostRead), listCategories)
+		protected.POST("/categories", RequirePermission(PermPostModerate), storeCategory)
+		protected.GET("/search", RequirePermission(PermPostRead, PermCommentRead), search)
+	}
+
+	// 旧的RPC风格路由，迁移期间保留，响应中带有Deprecation头
+	if LegacyRoutes {
+		legacy := r.Group("/api")
+		legacy.Use(AuthMiddleware())
+		{
+			legacy.POST("/create_post", Deprecated("/api/posts"), RequirePermission(PermPostCreate), createPost)
+			legacy.GET("/get_post", Deprecated("/api/posts"), RequirePermission(PermPostRead), getPost)
+			legacy.POST("/update_post", Deprecated("/api/posts/{id}"), RequireScope(ScopePostsWrite), updatePost)
+			legacy.POST("/delete_post", Deprecated("/api/posts/{id}"), RequireScope(ScopePostsWrite), deletePost)
+			legacy.POST("/create_comment", Deprecated("/api/posts/{id}/comments"), RequirePermission(PermCommentCreate), RateLimitMiddleware("comment", CommentRateLimit, KeyByUser), createComment)
+			legacy.GET("/get_comment", Deprecated("/api/posts/{id}/comments"), RequirePermission(PermCommentRead), getComment)
+			legacy.POST("/delete_comment", Deprecated("/api/comments/{id}"), RequireScope(ScopeCommentsWrite), deleteComment)
+		}
 	}
 
 	// 可选认证的路由（有token时提供额外信息，没有token也能访问）
@@ -293,12 +591,12 @@ func RegisterRoutes(r *gin.Engine) {
 			// 如果用户已认证，可以提供个性化内容
 			if userID, exists := GetCurrentUserID(c); exists {
 				c.JSON(http.StatusOK, gin.H{
-					"message": "test for authenticated user",
+					"message": T(c, "public_test_user"),
 					"user_id": userID,
 				})
 			} else {
 				c.JSON(http.StatusOK, gin.H{
-					"message": "Public test",
+					"message": T(c, "public_test"),
 				})
 			}
 		})
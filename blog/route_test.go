@@ -0,0 +1,165 @@
+package blog
+
+import (
+	"bytes"
+	"encoding/json"
+	model "job/blog/Model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter 使用内存SQLite创建完整路由
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	setupTestDB(t)
+	rateLimitStore = NewMemoryRateLimitStore()
+	mailer = &MemoryMailer{}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterRoutes(r)
+	return r
+}
+
+// doRequest 发送JSON请求，token不为空时携带Authorization头
+func doRequest(r http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData 解析统一响应格式中data字段的内容
+func decodeData(w *httptest.ResponseRecorder, v any) error {
+	var env struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		return err
+	}
+	return json.Unmarshal(env.Data, v)
+}
+
+// loginAs 创建指定角色的用户并返回访问Token
+func loginAs(t *testing.T, username, role string) (*model.User, string) {
+	t.Helper()
+	user := model.User{Username: username, Email: username + "@example.com", Password: "x", Role: role}
+	if err := GetDB().Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	pair, err := IssueTokenPair(&user)
+	if err != nil {
+		t.Fatalf("IssueTokenPair: %v", err)
+	}
+	return &user, pair.AccessToken
+}
+
+func TestReaderCannotCreatePost(t *testing.T) {
+	r := newTestRouter(t)
+	_, token := loginAs(t, "reader", RoleReader)
+
+	w := doRequest(r, http.MethodPost, "/api/create_post", token, gin.H{"Title": "t", "Content": "c"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403: %s", w.Code, w.Body)
+	}
+}
+
+func TestModeratorCanDeleteOthersPost(t *testing.T) {
+	r := newTestRouter(t)
+	author, _ := loginAs(t, "author", RoleAuthor)
+	_, otherToken := loginAs(t, "other", RoleAuthor)
+	_, editorToken := loginAs(t, "editor", RoleEditor)
+
+	post := model.Post{Title: "t", Content: "c", UserID: int(author.ID)}
+	GetDB().Create(&post)
+	path := "/api/delete_post?id=" + strconv.Itoa(int(post.ID))
+
+	if w := doRequest(r, http.MethodPost, path, otherToken, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("other author status = %d, want 403", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, path, editorToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("editor status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestPostResourceRoutes(t *testing.T) {
+	r := newTestRouter(t)
+	_, token := loginAs(t, "author", RoleAuthor)
+
+	w := doRequest(r, http.MethodPost, "/api/posts", token, gin.H{"title": "hello", "content": "world"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body)
+	}
+	location := w.Header().Get("Location")
+	if location == "" {
+		t.Fatal("missing Location header")
+	}
+
+	w = doRequest(r, http.MethodPatch, location, token, gin.H{"title": "updated"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", w.Code, w.Body)
+	}
+	var post model.Post
+	decodeData(w, &post)
+	if post.Title != "updated" || post.Content != "world" {
+		t.Fatalf("patched post = %+v", post)
+	}
+
+	w = doRequest(r, http.MethodPost, location+"/comments", token, gin.H{"content": "nice"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("comment status = %d: %s", w.Code, w.Body)
+	}
+	commentLocation := w.Header().Get("Location")
+
+	if w = doRequest(r, http.MethodDelete, commentLocation, token, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete comment status = %d: %s", w.Code, w.Body)
+	}
+	if w = doRequest(r, http.MethodDelete, location, token, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete post status = %d: %s", w.Code, w.Body)
+	}
+	if w = doRequest(r, http.MethodGet, location, token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted post status = %d", w.Code)
+	}
+}
+
+func TestLegacyRoutesFlag(t *testing.T) {
+	LegacyRoutes = false
+	t.Cleanup(func() { LegacyRoutes = true })
+	r := newTestRouter(t)
+	_, token := loginAs(t, "author", RoleAuthor)
+
+	if w := doRequest(r, http.MethodGet, "/api/get_post", token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("legacy route status = %d, want 404", w.Code)
+	}
+}
+
+func TestLegacyCreateCommentReportsFailure(t *testing.T) {
+	r := newTestRouter(t)
+	user, token := loginAs(t, "reader", RoleReader)
+	post := model.Post{Title: "t", Content: "c", UserID: int(user.ID), Status: model.PostStatusPublished}
+	GetDB().Create(&post)
+
+	// 评论表不可用时插入失败，不能再返回201
+	if err := GetDB().Migrator().DropTable(&model.Comment{}); err != nil {
+		t.Fatal(err)
+	}
+	w := doRequest(r, http.MethodPost, "/api/create_comment", token, gin.H{"PostID": post.ID, "Content": "hi"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("create comment status = %d: %s", w.Code, w.Body)
+	}
+	GetDB().First(&post, post.ID)
+	if post.CommentsCount != 0 {
+		t.Fatalf("comment count = %d, want 0", post.CommentsCount)
+	}
+}
@@ -0,0 +1,254 @@
+package blog
+
+import (
+	"context"
+	model "job/blog/Model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 搜索文档类型
+const (
+	SearchKindPost    = "post"
+	SearchKindComment = "comment"
+)
+
+// SearchDocument 被索引的文档
+type SearchDocument struct {
+	Kind    string
+	ID      uint
+	PostID  uint // 评论所属文章，文章时与ID相同
+	Title   string
+	Content string
+}
+
+// SearchQuery 搜索条件
+type SearchQuery struct {
+	Text   string
+	Kind   string // 为空表示搜索全部类型
+	Offset int
+	Limit  int
+}
+
+// SearchHit 搜索命中结果，Title和Snippet已转义并用<mark>高亮
+type SearchHit struct {
+	Kind    string  `json:"type"`
+	ID      uint    `json:"id"`
+	PostID  uint    `json:"post_id"`
+	Score   float64 `json:"score"`
+	Title   string  `json:"title,omitempty"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchResult 一页搜索结果
+type SearchResult struct {
+	Hits  []SearchHit
+	Total int64
+}
+
+// SearchIndex 搜索索引接口，可以替换为外部搜索引擎实现
+type SearchIndex interface {
+	Index(doc SearchDocument) error
+	Remove(kind string, id uint) error
+	Contains(kind string, id uint) bool
+	Search(q SearchQuery) (*SearchResult, error)
+}
+
+// 全局搜索索引，由InitSearch初始化
+var searchIndex SearchIndex = NewInvertedIndex()
+
+// InitSearch 设置搜索索引，注册模型钩子并用数据库中的现有内容重建索引
+func InitSearch(index SearchIndex) error {
+	searchIndex = index
+	model.SearchHook = searchSync{}
+	return RebuildSearchIndex()
+}
+
+// RebuildSearchIndex 从数据库全量重建索引
+func RebuildSearchIndex() error {
+	var posts []model.Post
+	err := GetDB().Where("status = ?", model.PostStatusPublished).FindInBatches(&posts, 500, func(tx *gorm.DB, batch int) error {
+		for i := range posts {
+			if err := searchIndex.Index(postDocument(&posts[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	// 只索引已发布文章下的评论，其他文章的评论不应出现在搜索结果中
+	var comments []model.Comment
+	return GetDB().Where("deleted = ? AND post_id IN (?)", false,
+		GetDB().Model(&model.Post{}).Select("id").Where("status = ?", model.PostStatusPublished),
+	).FindInBatches(&comments, 500, func(tx *gorm.DB, batch int) error {
+		for i := range comments {
+			if err := searchIndex.Index(commentDocument(&comments[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func postDocument(p *model.Post) SearchDocument {
+	return SearchDocument{Kind: SearchKindPost, ID: p.ID, PostID: p.ID, Title: p.Title, Content: p.Content}
+}
+
+func commentDocument(cm *model.Comment) SearchDocument {
+	return SearchDocument{Kind: SearchKindComment, ID: cm.ID, PostID: uint(cm.PostID), Content: cm.Content}
+}
+
+// searchOpsKey 事务上下文中保存待执行索引操作的键
+type searchOpsKey struct{}
+
+// searchOps 事务中产生的索引操作，事务提交后按顺序执行，回滚时丢弃
+type searchOps struct {
+	ops []func(db *gorm.DB) error
+}
+
+// withSearchOps 返回带有待执行索引操作列表的上下文，由inTransaction使用
+func withSearchOps(ctx context.Context) (context.Context, *searchOps) {
+	ops := &searchOps{}
+	return context.WithValue(ctx, searchOpsKey{}, ops), ops
+}
+
+// apply 执行事务中积累的索引操作，数据已经提交，失败时只记录日志
+func (s *searchOps) apply(ctx context.Context) {
+	for _, op := range s.ops {
+		if err := op(GetDB()); err != nil {
+			logger.WarnContext(ctx, "Failed to update search index", "error", err)
+		}
+	}
+}
+
+// indexAfterCommit 在inTransaction开启的事务中把索引操作推迟到提交之后，否则立即执行
+// op需要读取数据库时使用传入的db：推迟执行时是提交后的连接，立即执行时是触发钩子的会话
+func indexAfterCommit(tx *gorm.DB, op func(db *gorm.DB) error) error {
+	if ops, ok := tx.Statement.Context.Value(searchOpsKey{}).(*searchOps); ok {
+		ops.ops = append(ops.ops, op)
+		return nil
+	}
+	return op(tx.Session(&gorm.Session{NewDB: true}))
+}
+
+// searchSync 实现model.SearchHook，在模型保存和删除后同步索引
+type searchSync struct{}
+
+// PostSaved 只索引已发布的文章，草稿、定时和归档的文章从索引中移除
+// 文章进入或离开已发布状态时，它的评论随之加入或移出索引，发布、撤回和定时发布都会经过这里
+func (searchSync) PostSaved(tx *gorm.DB, p *model.Post) error {
+	doc := postDocument(p)
+	published := p.Status == model.PostStatusPublished
+	return indexAfterCommit(tx, func(db *gorm.DB) error {
+		wasPublished := searchIndex.Contains(SearchKindPost, doc.ID)
+		var err error
+		if published {
+			err = searchIndex.Index(doc)
+		} else {
+			err = searchIndex.Remove(SearchKindPost, doc.ID)
+		}
+		if err != nil || published == wasPublished {
+			return err
+		}
+		return syncPostComments(db, doc.ID, published)
+	})
+}
+
+func (searchSync) PostDeleted(tx *gorm.DB, id uint) error {
+	return indexAfterCommit(tx, func(db *gorm.DB) error {
+		if !searchIndex.Contains(SearchKindPost, id) {
+			return nil
+		}
+		if err := searchIndex.Remove(SearchKindPost, id); err != nil {
+			return err
+		}
+		return syncPostComments(db, id, false)
+	})
+}
+
+// syncPostComments 文章已发布时索引其下未删除的评论，否则把这些评论从索引中移除
+func syncPostComments(db *gorm.DB, postID uint, published bool) error {
+	var comments []model.Comment
+	if err := db.Where("post_id = ?", postID).Find(&comments).Error; err != nil {
+		return err
+	}
+	for i := range comments {
+		var err error
+		if published && !comments[i].Deleted {
+			err = searchIndex.Index(commentDocument(&comments[i]))
+		} else {
+			err = searchIndex.Remove(SearchKindComment, comments[i].ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommentSaved 只索引已发布文章下未删除的评论
+func (searchSync) CommentSaved(tx *gorm.DB, cm *model.Comment) error {
+	doc := commentDocument(cm)
+	if cm.Deleted {
+		return indexAfterCommit(tx, func(*gorm.DB) error { return searchIndex.Remove(SearchKindComment, doc.ID) })
+	}
+	var post model.Post
+	err := tx.Session(&gorm.Session{NewDB: true}).Select("status").Where("id = ?", cm.PostID).Limit(1).Find(&post).Error
+	if err != nil {
+		return err
+	}
+	if post.Status != model.PostStatusPublished {
+		return indexAfterCommit(tx, func(*gorm.DB) error { return searchIndex.Remove(SearchKindComment, doc.ID) })
+	}
+	return indexAfterCommit(tx, func(*gorm.DB) error { return searchIndex.Index(doc) })
+}
+
+func (searchSync) CommentDeleted(tx *gorm.DB, id uint) error {
+	return indexAfterCommit(tx, func(*gorm.DB) error { return searchIndex.Remove(SearchKindComment, id) })
+}
+
+// search GET /api/search?q=关键字&type=post|comment&page=1&size=20
+func search(c *gin.Context) {
+	text := c.Query("q")
+	if text == "" {
+		respondError(c, invalidQuery("invalid_query.search_text"))
+		return
+	}
+	kind := c.Query("type")
+	switch kind {
+	case "", SearchKindPost, SearchKindComment:
+	default:
+		respondError(c, invalidQuery("invalid_query.search_type"))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(c, invalidQuery("invalid_query.page"))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(DefaultPageSize)))
+	if err != nil || size < 1 || size > MaxPageSize {
+		respondError(c, invalidQuery("invalid_query.size", MaxPageSize))
+		return
+	}
+
+	res, err := searchIndex.Search(SearchQuery{Text: text, Kind: kind, Offset: (page - 1) * size, Limit: size})
+	if err != nil {
+		respondError(c, InternalError("Search failed", err))
+		return
+	}
+
+	links := PageLinks{}
+	if int64(page*size) < res.Total {
+		links.Next = pageURL(c, map[string]string{"page": strconv.Itoa(page + 1)})
+	}
+	if page > 1 {
+		links.Prev = pageURL(c, map[string]string{"page": strconv.Itoa(page - 1)})
+	}
+	respondPage(c, res.Hits, Pagination{Total: res.Total, Page: page, Size: size, Sort: "relevance"}, links)
+}
@@ -0,0 +1,274 @@
+package blog
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25参数
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2 // 标题中的词按出现两次计算
+	snippetLen  = 80
+)
+
+type docKey struct {
+	kind string
+	id   uint
+}
+
+// InvertedIndex 内存倒排索引，英文按单词切分，中日韩文字按二元组切分，使用BM25排序
+type InvertedIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[docKey]int // 词 -> 文档 -> 词频
+	docs     map[docKey]SearchDocument
+	docLen   map[docKey]int
+	totalLen int
+}
+
+// NewInvertedIndex 创建空的内存倒排索引
+func NewInvertedIndex() *InvertedIndex {
+	return &InvertedIndex{
+		postings: map[string]map[docKey]int{},
+		docs:     map[docKey]SearchDocument{},
+		docLen:   map[docKey]int{},
+	}
+}
+
+// Index 添加或更新文档
+func (idx *InvertedIndex) Index(doc SearchDocument) error {
+	key := docKey{doc.Kind, doc.ID}
+	freqs := map[string]int{}
+	length := 0
+	for _, term := range Tokenize(doc.Title) {
+		freqs[term] += titleWeight
+		length += titleWeight
+	}
+	for _, term := range Tokenize(doc.Content) {
+		freqs[term]++
+		length++
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(key)
+	for term, tf := range freqs {
+		if idx.postings[term] == nil {
+			idx.postings[term] = map[docKey]int{}
+		}
+		idx.postings[term][key] = tf
+	}
+	idx.docs[key] = doc
+	idx.docLen[key] = length
+	idx.totalLen += length
+	return nil
+}
+
+// Remove 删除文档
+func (idx *InvertedIndex) Remove(kind string, id uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(docKey{kind, id})
+	return nil
+}
+
+// Contains 文档是否在索引中
+func (idx *InvertedIndex) Contains(kind string, id uint) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	_, ok := idx.docs[docKey{kind, id}]
+	return ok
+}
+
+func (idx *InvertedIndex) remove(key docKey) {
+	doc, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+	for _, term := range append(Tokenize(doc.Title), Tokenize(doc.Content)...) {
+		if posting := idx.postings[term]; posting != nil {
+			delete(posting, key)
+			if len(posting) == 0 {
+				delete(idx.postings, term)
+			}
+		}
+	}
+	idx.totalLen -= idx.docLen[key]
+	delete(idx.docs, key)
+	delete(idx.docLen, key)
+}
+
+// Search 按BM25得分排序返回一页结果
+func (idx *InvertedIndex) Search(q SearchQuery) (*SearchResult, error) {
+	terms := uniqueTerms(Tokenize(q.Text))
+	result := &SearchResult{Hits: []SearchHit{}}
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	n := float64(len(idx.docs))
+	avgLen := float64(idx.totalLen) / math.Max(n, 1)
+	scores := map[docKey]float64{}
+	for _, term := range terms {
+		posting := idx.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for key, tf := range posting {
+			if q.Kind != "" && key.kind != q.Kind {
+				continue
+			}
+			f := float64(tf)
+			dl := float64(idx.docLen[key])
+			scores[key] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*dl/avgLen))
+		}
+	}
+
+	keys := make([]docKey, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		return keys[i].id > keys[j].id
+	})
+
+	result.Total = int64(len(keys))
+	if q.Offset >= len(keys) {
+		return result, nil
+	}
+	keys = keys[q.Offset:min(q.Offset+q.Limit, len(keys))]
+	for _, key := range keys {
+		doc := idx.docs[key]
+		result.Hits = append(result.Hits, SearchHit{
+			Kind:    doc.Kind,
+			ID:      doc.ID,
+			PostID:  doc.PostID,
+			Score:   math.Round(scores[key]*1000) / 1000,
+			Title:   Highlight(doc.Title, terms, 0),
+			Snippet: Highlight(doc.Content, terms, snippetLen),
+		})
+	}
+	return result, nil
+}
+
+// isCJK 中日韩文字按字切分
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Tokenize 分词：英文和数字按单词切分并转为小写，连续的中日韩文字切分为二元组（单字时保留单字）
+func Tokenize(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isCJK(r):
+			j := i
+			for j < len(runes) && isCJK(runes[j]) {
+				j++
+			}
+			if j-i == 1 {
+				tokens = append(tokens, string(runes[i]))
+			}
+			for k := i; k+1 < j; k++ {
+				tokens = append(tokens, string(runes[k:k+2]))
+			}
+			i = j
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && !isCJK(runes[j]) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(string(runes[i:j])))
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Highlight 用<mark>标记命中的词并转义其余HTML
+// maxLen大于0时截取第一个命中位置附近的片段
+func Highlight(text string, terms []string, maxLen int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记命中的字符，英文单词要求完整匹配
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		tr := []rune(term)
+		word := !isCJK(tr[0])
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if string(lower[i:i+len(tr)]) != term {
+				continue
+			}
+			if word && (i > 0 && isWordRune(lower[i-1]) || i+len(tr) < len(lower) && isWordRune(lower[i+len(tr)])) {
+				continue
+			}
+			for k := i; k < i+len(tr); k++ {
+				marked[k] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if maxLen > 0 && len(runes) > maxLen {
+		start = max(0, first-maxLen/4)
+		end = min(len(runes), start+maxLen)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i+1 == end || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return !isCJK(r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,141 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Go语言博客, hello World! 中")
+	want := []string{"go", "语言", "言博", "博客", "hello", "world", "中"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("Go is <fun>, going 博客", []string{"go", "博客"}, 0)
+	want := "<mark>Go</mark> is &lt;fun&gt;, going <mark>博客</mark>"
+	if got != want {
+		t.Fatalf("Highlight = %q, want %q", got, want)
+	}
+}
+
+func TestInvertedIndexRanking(t *testing.T) {
+	idx := NewInvertedIndex()
+	idx.Index(SearchDocument{Kind: SearchKindPost, ID: 1, Title: "Rust 入门", Content: "学习 Go 语言之前"})
+	idx.Index(SearchDocument{Kind: SearchKindPost, ID: 2, Title: "Go 语言入门", Content: "Go 语言是一门简单的语言"})
+	idx.Index(SearchDocument{Kind: SearchKindComment, ID: 1, PostID: 2, Content: "写得好"})
+
+	res, err := idx.Search(SearchQuery{Text: "go语言", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 2 || res.Hits[0].ID != 2 {
+		t.Fatalf("unexpected hits: %+v", res.Hits)
+	}
+
+	idx.Remove(SearchKindPost, 2)
+	res, _ = idx.Search(SearchQuery{Text: "go语言", Limit: 10})
+	if res.Total != 1 || res.Hits[0].ID != 1 {
+		t.Fatalf("after remove: %+v", res.Hits)
+	}
+}
+
+func TestSearchEndpointSyncsWithHooks(t *testing.T) {
+	r := newTestRouter(t)
+	if err := InitSearch(NewInvertedIndex()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { model.SearchHook = nil })
+	user, token := loginAs(t, "author", RoleAuthor)
+
+	post := model.Post{Title: "分布式系统", Content: "一致性协议", UserID: int(user.ID)}
+	GetDB().Create(&post)
+	GetDB().Create(&model.Comment{Content: "讲得很清楚的协议", PostID: int(post.ID), UserID: int(user.ID)})
+
+	search := func(q string) []SearchHit {
+		w := doRequest(r, http.MethodGet, "/api/search?q="+url.QueryEscape(q), token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("search status = %d: %s", w.Code, w.Body)
+		}
+		var body struct{ Data []SearchHit }
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Data
+	}
+
+	if hits := search("协议"); len(hits) != 2 {
+		t.Fatalf("hits = %+v, want post and comment", hits)
+	}
+	GetDB().Delete(&post)
+	if hits := search("分布式"); len(hits) != 0 {
+		t.Fatalf("deleted post still searchable: %+v", hits)
+	}
+}
+
+func TestSearchHidesCommentsOnUnpublishedPosts(t *testing.T) {
+	r := newTestRouter(t)
+	if err := InitSearch(NewInvertedIndex()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { model.SearchHook = nil })
+	author, _ := loginAs(t, "author", RoleAuthor)
+	_, token := loginAs(t, "reader", RoleReader)
+
+	draft := model.Post{Title: "草稿", Content: "c", UserID: int(author.ID), Status: model.PostStatusDraft}
+	GetDB().Create(&draft)
+	GetDB().Create(&model.Comment{Content: "秘密计划", PostID: int(draft.ID), UserID: int(author.ID)})
+
+	search := func() []SearchHit {
+		w := doRequest(r, http.MethodGet, "/api/search?q="+url.QueryEscape("秘密"), token, nil)
+		var body struct{ Data []SearchHit }
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Data
+	}
+	if hits := search(); len(hits) != 0 {
+		t.Fatalf("comment on draft is searchable: %+v", hits)
+	}
+	// 重建索引同样跳过未发布文章的评论
+	if err := RebuildSearchIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if hits := search(); len(hits) != 0 {
+		t.Fatalf("comment on draft is searchable after rebuild: %+v", hits)
+	}
+}
+
+func TestSearchIndexIgnoresRolledBackWrites(t *testing.T) {
+	r := newTestRouter(t)
+	if err := InitSearch(NewInvertedIndex()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { model.SearchHook = nil })
+	_, token := loginAs(t, "author", RoleAuthor)
+
+	// 分类不存在时事务回滚，文章不能留在索引中
+	w := doRequest(r, http.MethodPost, "/api/posts", token, gin.H{"title": "幽灵文章", "content": "c", "categories": []string{"missing"}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body)
+	}
+	if res, _ := searchIndex.Search(SearchQuery{Text: "幽灵", Limit: 10}); res.Total != 0 {
+		t.Fatalf("rolled back post is searchable: %+v", res.Hits)
+	}
+
+	w = doRequest(r, http.MethodPost, "/api/posts", token, gin.H{"title": "真实文章", "content": "c"})
+	var post model.Post
+	decodeData(w, &post)
+	w = doRequest(r, http.MethodPatch, fmt.Sprintf("/api/posts/%d", post.ID), token, gin.H{"title": "修改标题", "categories": []string{"missing"}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("patch status = %d: %s", w.Code, w.Body)
+	}
+	if res, _ := searchIndex.Search(SearchQuery{Text: "真实", Limit: 10}); res.Total != 1 {
+		t.Fatalf("index changed by rolled back update: %+v", res.Hits)
+	}
+}
@@ -0,0 +1,133 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 支持的存储驱动
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// ErrObjectNotFound 存储中不存在指定的对象
+var ErrObjectNotFound = errors.New("object not found")
+
+// StorageConfig 附件存储配置
+type StorageConfig struct {
+	Driver        string   `yaml:"driver"`          // 存储驱动：local、s3
+	LocalDir      string   `yaml:"local_dir"`       // local驱动的根目录
+	MaxUploadSize int      `yaml:"max_upload_size"` // 单个文件的最大字节数
+	S3            S3Config `yaml:"s3"`
+}
+
+// S3Config S3兼容存储配置（AWS S3、MinIO等），使用路径风格的地址
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"` // 例如 https://s3.us-east-1.amazonaws.com 或 http://localhost:9000
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+// Storage 附件存储接口，key使用"/"分隔
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// 全局附件存储，由InitStorage初始化
+var storage Storage = NewLocalStorage("uploads")
+
+// MaxUploadSize 单个上传文件的最大字节数
+var MaxUploadSize int64 = 10 << 20
+
+// InitStorage 根据配置初始化附件存储
+func InitStorage(cfg StorageConfig) error {
+	switch cfg.Driver {
+	case StorageLocal:
+		storage = NewLocalStorage(cfg.LocalDir)
+	case StorageS3:
+		s3, err := NewS3Storage(cfg.S3)
+		if err != nil {
+			return err
+		}
+		storage = s3
+	default:
+		return fmt.Errorf("unsupported storage driver %q", cfg.Driver)
+	}
+	MaxUploadSize = int64(cfg.MaxUploadSize)
+	return nil
+}
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	Dir string
+}
+
+// NewLocalStorage 创建以dir为根目录的本地存储
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{Dir: dir}
+}
+
+// path 把key转换为根目录下的路径，拒绝跳出根目录的key
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入临时文件再重命名，避免读到写了一半的文件
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
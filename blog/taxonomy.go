@@ -0,0 +1,187 @@
+package blog
+
+import (
+	"errors"
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 标签限制
+const (
+	MaxTagsPerPost = 10
+	MaxTagLength   = 32
+)
+
+var (
+	ErrInvalidTag      = ValidationError("invalid_tag", fmt.Sprintf("tags must be 1-%d characters", MaxTagLength))
+	ErrTooManyTags     = ValidationError("too_many_tags", fmt.Sprintf("a post can have at most %d tags", MaxTagsPerPost))
+	ErrUnknownCategory = ValidationError("unknown_category", "unknown category")
+	ErrCategoryExists  = ConflictError("category_exists", "Category already exists")
+)
+
+// 创建分类请求结构
+type CreateCategoryRequest struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Slug        string `json:"slug" binding:"required,max=64"`
+	Description string `json:"description"`
+}
+
+// TagCount 标签云中的一项
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// normalizeTags 去除首尾空白、转为小写并去重
+func normalizeTags(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, name := range names {
+		name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+		if name == "" || utf8.RuneCountInString(name) > MaxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	if len(out) > MaxTagsPerPost {
+		return nil, ErrTooManyTags
+	}
+	return out, nil
+}
+
+// setPostTags 用给定的标签替换文章的标签，不存在的标签自动创建
+func setPostTags(tx *gorm.DB, post *model.Post, names []string) error {
+	names, err := normalizeTags(names)
+	if err != nil {
+		return err
+	}
+	tags := []model.Tag{}
+	if len(names) > 0 {
+		for _, name := range names {
+			tags = append(tags, model.Tag{Name: name})
+		}
+		// 并发创建同名标签时忽略唯一索引冲突，再统一查询
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+		tags = tags[:0]
+		if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(post).Association("Tags").Replace(tags)
+}
+
+// setPostCategories 用给定的分类（按slug）替换文章的分类，分类必须已经存在
+func setPostCategories(tx *gorm.DB, post *model.Post, slugs []string) error {
+	categories := []model.Category{}
+	if len(slugs) > 0 {
+		if err := tx.Where("slug IN ?", slugs).Find(&categories).Error; err != nil {
+			return err
+		}
+		for _, slug := range slugs {
+			found := false
+			for _, cat := range categories {
+				found = found || cat.Slug == slug
+			}
+			if !found {
+				return ErrUnknownCategory.WithDetail("unknown_category.slug", slug)
+			}
+		}
+	}
+	return tx.Model(post).Association("Categories").Replace(categories)
+}
+
+// setPostTaxonomy 更新文章的标签和分类，参数为nil时保持不变
+func setPostTaxonomy(tx *gorm.DB, post *model.Post, tags, categories *[]string) error {
+	if tags != nil {
+		if err := setPostTags(tx, post, *tags); err != nil {
+			return err
+		}
+	}
+	if categories != nil {
+		return setPostCategories(tx, post, *categories)
+	}
+	return nil
+}
+
+// applyTaxonomyFilters 按 tag（标签名）和 category（分类slug）过滤文章
+func applyTaxonomyFilters(c *gin.Context, q *gorm.DB) *gorm.DB {
+	if tag := c.Query("tag"); tag != "" {
+		q = q.Where("posts.id IN (?)", GetDB().Table("post_tags").Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name = ? AND tags.deleted_at IS NULL", strings.ToLower(strings.TrimSpace(tag))))
+	}
+	if category := c.Query("category"); category != "" {
+		q = q.Where("posts.id IN (?)", GetDB().Table("post_categories").Select("post_categories.post_id").
+			Joins("JOIN categories ON categories.id = post_categories.category_id").
+			Where("categories.slug = ? AND categories.deleted_at IS NULL", category))
+	}
+	return q
+}
+
+// tagCloud GET /api/tags?limit=50
+// 返回已发布文章中使用的标签及次数，按次数降序
+func tagCloud(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > MaxPageSize {
+		respondError(c, invalidQuery("invalid_query.limit", MaxPageSize))
+		return
+	}
+	cloud := []TagCount{}
+	err = GetDB().Table("tags").
+		Select("tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL AND posts.status = ?", model.PostStatusPublished).
+		Where("tags.deleted_at IS NULL").
+		Group("tags.id, tags.name").
+		Order("count DESC, name").
+		Limit(limit).
+		Scan(&cloud).Error
+	if err != nil {
+		respondError(c, InternalError("Failed to load tags", err))
+		return
+	}
+	respondData(c, http.StatusOK, cloud)
+}
+
+// listCategories GET /api/categories
+func listCategories(c *gin.Context) {
+	var categories []model.Category
+	if err := GetDB().Order("name").Find(&categories).Error; err != nil {
+		respondError(c, InternalError("Failed to load categories", err))
+		return
+	}
+	respondData(c, http.StatusOK, categories)
+}
+
+// storeCategory POST /api/categories
+func storeCategory(c *gin.Context) {
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	category := model.Category{Name: req.Name, Slug: req.Slug, Description: req.Description}
+	// 别名重复（包括并发创建同一别名）时由唯一索引拒绝
+	if err := GetDB().Create(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondError(c, ErrCategoryExists)
+			return
+		}
+		respondError(c, InternalError("Failed to create category", err))
+		return
+	}
+	respondData(c, http.StatusCreated, category)
+}
@@ -0,0 +1,64 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	model "job/blog/Model"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostTagsAndCategories(t *testing.T) {
+	r := newTestRouter(t)
+	_, token := loginAs(t, "author", RoleAuthor)
+	_, editorToken := loginAs(t, "editor", RoleEditor)
+
+	if w := doRequest(r, http.MethodPost, "/api/categories", token, gin.H{"name": "技术", "slug": "tech"}); w.Code != http.StatusForbidden {
+		t.Fatalf("author create category status = %d, want 403", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/api/categories", editorToken, gin.H{"name": "技术", "slug": "tech"}); w.Code != http.StatusCreated {
+		t.Fatalf("create category status = %d: %s", w.Code, w.Body)
+	}
+	if w := doRequest(r, http.MethodPost, "/api/categories", editorToken, gin.H{"name": "科技", "slug": "tech"}); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate slug status = %d: %s", w.Code, w.Body)
+	}
+
+	create := func(title string, tags []string, categories []string) uint {
+		w := doRequest(r, http.MethodPost, "/api/posts", token, gin.H{"title": title, "content": "c", "tags": tags, "categories": categories})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s status = %d: %s", title, w.Code, w.Body)
+		}
+		var post model.Post
+		decodeData(w, &post)
+		return post.ID
+	}
+	a := create("a", []string{"Go", " go ", "Web"}, []string{"tech"})
+	create("b", []string{"go"}, nil)
+	create("c", []string{"rust"}, nil)
+
+	if w := doRequest(r, http.MethodPost, "/api/posts", token, gin.H{"title": "x", "content": "c", "categories": []string{"missing"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown category status = %d, want 400", w.Code)
+	}
+
+	if got := titles(getPostPage(t, r, "/api/posts?tag=GO&sort=title", token).Data); got != "ab" {
+		t.Fatalf("tag=go posts = %q", got)
+	}
+	page := getPostPage(t, r, "/api/posts?category=tech", token)
+	if titles(page.Data) != "a" || len(page.Data[0].Tags) != 2 || len(page.Data[0].Categories) != 1 {
+		t.Fatalf("category=tech posts = %+v", page.Data)
+	}
+
+	// 替换标签后标签云随之变化
+	if w := doRequest(r, http.MethodPatch, fmt.Sprintf("/api/posts/%d", a), token, gin.H{"tags": []string{"rust"}}); w.Code != http.StatusOK {
+		t.Fatalf("patch status = %d: %s", w.Code, w.Body)
+	}
+	w := doRequest(r, http.MethodGet, "/api/tags", token, nil)
+	var cloud struct{ Data []TagCount }
+	json.Unmarshal(w.Body.Bytes(), &cloud)
+	want := []TagCount{{"rust", 2}, {"go", 1}}
+	if fmt.Sprint(cloud.Data) != fmt.Sprint(want) {
+		t.Fatalf("tag cloud = %+v, want %+v", cloud.Data, want)
+	}
+}
@@ -0,0 +1,341 @@
+package blog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	model "job/blog/Model"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PurposeMFAChallenge 两步登录中第一步签发的挑战Token的用途
+const PurposeMFAChallenge = "mfa-challenge"
+
+// 两步验证参数
+var (
+	MFAChallengeTTL   = 5 * time.Minute
+	RecoveryCodeCount = 10
+)
+
+// 两步验证相关错误
+var (
+	ErrInvalidSecondFactor = ValidationError("invalid_code", "Invalid verification code") // 验证码或恢复码错误
+	ErrInvalidChallenge    = UnauthorizedError("invalid_challenge", "Invalid or expired challenge token")
+	ErrTOTPAlreadyEnabled  = ConflictError("totp_enabled", "Two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled     = ValidationError("totp_not_enrolled", "Two-factor authentication has not been enrolled")
+	ErrTOTPNotEnabled      = ValidationError("totp_not_enabled", "Two-factor authentication is not enabled")
+)
+
+// errAborted 事务中已经写入了错误响应，只需要回滚
+var errAborted = errors.New("request aborted")
+
+// 两步验证码请求结构，code可以是验证器应用中的6位数字，也可以是恢复码
+type SecondFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// 两步登录请求结构
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// newRecoveryCodes 生成新的恢复码并替换旧的，返回明文（只展示这一次）
+func newRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, RecoveryCodeCount)
+	records := make([]model.RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = model.RecoveryCode{UserID: userID, CodeHash: hashRefreshToken(raw)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor 校验验证码或恢复码，通过后立即记为已使用
+// 条件更新保证并发请求中同一个验证码或恢复码只有一个能成功
+func verifySecondFactor(tx *gorm.DB, user *model.User, code string, now time.Time) error {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == TOTPDigits {
+		step, ok := ValidateTOTP(user.TOTPSecret, code, now, user.TOTPLastStep)
+		if !ok {
+			return ErrInvalidSecondFactor
+		}
+		res := tx.Model(&model.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).
+			UpdateColumn("totp_last_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidSecondFactor
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	raw := strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	res := tx.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRefreshToken(raw)).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidSecondFactor
+	}
+	return nil
+}
+
+// currentUser 从数据库加载当前登录的用户
+func currentUser(c *gin.Context) (*model.User, bool) {
+	id, _ := GetCurrentUserID(c)
+	var user model.User
+	if err := GetDB().First(&user, id).Error; err != nil {
+		respondError(c, ErrUnauthorized)
+		return nil, false
+	}
+	return &user, true
+}
+
+// bindSecondFactor 解析请求中的验证码并校验，失败时已写入响应
+func bindSecondFactor(c *gin.Context, tx *gorm.DB, user *model.User) bool {
+	var req SecondFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return false
+	}
+	if err := verifySecondFactor(tx, user, req.Code, time.Now()); err != nil {
+		respondError(c, classifyError(err, "Failed to verify code"))
+		return false
+	}
+	return true
+}
+
+// twoFactorStatus GET /api/profile/2fa
+func twoFactorStatus(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var remaining int64
+	GetDB().Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+	mfa, _ := c.Get("mfa")
+	respondData(c, http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabledAt != nil,
+		"enabled_at":               user.TOTPEnabledAt,
+		"recovery_codes_remaining": remaining,
+		"session_mfa":              mfa == true,
+	})
+}
+
+// enrollTwoFactor POST /api/profile/2fa
+// 生成新的密钥，确认之前不生效，重复调用会替换未确认的密钥
+func enrollTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		respondError(c, ErrTOTPAlreadyEnabled)
+		return
+	}
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		respondError(c, InternalError("Failed to generate secret", err))
+		return
+	}
+	if err := GetDB().Model(user).Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		respondError(c, InternalError("Failed to save secret", err))
+		return
+	}
+	respondData(c, http.StatusOK, gin.H{"secret": secret, "otpauth_uri": TOTPURI(secret, user.Email)})
+}
+
+// confirmTwoFactor POST /api/profile/2fa/confirm
+// 用验证器应用中的验证码确认登记，返回只展示一次的恢复码
+func confirmTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		respondError(c, ErrTOTPAlreadyEnabled)
+		return
+	}
+	if user.TOTPSecret == "" {
+		respondError(c, ErrTOTPNotEnrolled)
+		return
+	}
+
+	var codes []string
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		// 确认之前没有恢复码，这里只能是验证码
+		if !bindSecondFactor(c, tx, user) {
+			return errAborted
+		}
+		if err := tx.Model(user).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = newRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if errors.Is(err, errAborted) {
+		return
+	}
+	if err != nil {
+		respondError(c, InternalError("Failed to enable two-factor authentication", err))
+		return
+	}
+	respondData(c, http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+}
+
+// disableTwoFactor DELETE /api/profile/2fa
+// 需要完成了两步验证的会话，并再次提供验证码或恢复码
+func disableTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		respondError(c, ErrTOTPNotEnabled)
+		return
+	}
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		if !bindSecondFactor(c, tx, user) {
+			return errAborted
+		}
+		err := tx.Model(user).Updates(map[string]any{"totp_secret": "", "totp_enabled_at": nil, "totp_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error
+	})
+	if errors.Is(err, errAborted) {
+		return
+	}
+	if err != nil {
+		respondError(c, InternalError("Failed to disable two-factor authentication", err))
+		return
+	}
+	respondData(c, http.StatusOK, gin.H{"enabled": false})
+}
+
+// regenerateRecoveryCodes POST /api/profile/2fa/recovery-codes
+// 旧的恢复码全部作废
+func regenerateRecoveryCodes(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		respondError(c, ErrTOTPNotEnabled)
+		return
+	}
+	var codes []string
+	err := GetDB().Transaction(func(tx *gorm.DB) error {
+		if !bindSecondFactor(c, tx, user) {
+			return errAborted
+		}
+		var err error
+		codes, err = newRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if errors.Is(err, errAborted) {
+		return
+	}
+	if err != nil {
+		respondError(c, InternalError("Failed to generate recovery codes", err))
+		return
+	}
+	respondData(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// startTwoFactorLogin 密码验证通过且用户启用了两步验证时，返回挑战Token而不是会话
+func startTwoFactorLogin(c *gin.Context, user *model.User) {
+	token, err := issueActionToken(user, PurposeMFAChallenge, MFAChallengeTTL)
+	if err != nil {
+		respondError(c, InternalError("Failed to generate token", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":    true,
+		"challenge_token": token,
+		"expires_in":      int64(MFAChallengeTTL.Seconds()),
+	})
+}
+
+// loginTwoFactor POST /auth/login/2fa
+// 两步登录的第二步：提交挑战Token和验证码（或恢复码），成功后签发带mfa标记的会话
+// 错误的验证码与密码错误一样计入登录失败次数
+func loginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, BindError(err))
+		return
+	}
+	user, claims, err := parseActionToken(req.ChallengeToken, PurposeMFAChallenge)
+	if err != nil || user.TOTPEnabledAt == nil {
+		respondError(c, ErrInvalidChallenge.WithCause(err))
+		return
+	}
+
+	email := normalizeEmail(user.Email)
+	wait, err := loginBlockedFor(email, c.ClientIP(), time.Now())
+	if err != nil {
+		respondError(c, InternalError("Login failed", err))
+		return
+	}
+	if wait > 0 {
+		recordLoginAttempt(c, &user.ID, email, false, LoginReasonLocked)
+		abortLoginLocked(c, wait)
+		return
+	}
+
+	err = GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := verifySecondFactor(tx, user, req.Code, time.Now()); err != nil {
+			return err
+		}
+		return consumeActionToken(tx, claims)
+	})
+	switch {
+	case errors.Is(err, ErrInvalidSecondFactor):
+		recordLoginAttempt(c, &user.ID, email, false, LoginReasonInvalidSecondFactor)
+		respondError(c, UnauthorizedError(ErrInvalidSecondFactor.Code, ErrInvalidSecondFactor.Message))
+		return
+	case errors.Is(err, ErrActionTokenUsed):
+		respondError(c, ErrInvalidChallenge)
+		return
+	case err != nil:
+		respondError(c, InternalError("Login failed", err))
+		return
+	}
+	recordLoginAttempt(c, &user.ID, email, true, "")
+
+	pair, err := IssueMFATokenPair(user)
+	if err != nil {
+		respondError(c, InternalError("Failed to generate token", err))
+		return
+	}
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    pair.ExpiresIn,
+		User:         user,
+	})
+}
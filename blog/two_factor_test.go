@@ -0,0 +1,141 @@
+package blog
+
+import (
+	"encoding/json"
+	model "job/blog/Model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RFC 6238 附录B中SHA1的测试向量（取后6位）
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 20000000000: "353130"}
+	for unix, want := range cases {
+		if got := totpCode(key, totpStep(time.Unix(unix, 0))); got != want {
+			t.Errorf("code at %d = %s, want %s", unix, got, want)
+		}
+	}
+
+	secret := totpEncoding.EncodeToString(key)
+	now := time.Unix(59, 0)
+	if step, ok := ValidateTOTP(secret, "287082", now, 0); !ok || step != 1 {
+		t.Fatal("valid code rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "287082", now, 1); ok {
+		t.Fatal("replayed code accepted")
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	r := newTestRouter(t)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	now := time.Now()
+	user := model.User{Username: "carol", Email: "carol@example.com", Password: string(hash), EmailVerifiedAt: &now}
+	GetDB().Create(&user)
+	pair, _ := IssueTokenPair(&user)
+	token := pair.AccessToken
+
+	w := doRequest(r, http.MethodPost, "/api/profile/2fa", token, nil)
+	var enroll struct {
+		Secret     string
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+	decodeData(w, &enroll)
+	if w.Code != http.StatusOK || enroll.Secret == "" || enroll.OtpauthURI == "" {
+		t.Fatalf("enroll: %d %s", w.Code, w.Body)
+	}
+	key, _ := totpEncoding.DecodeString(enroll.Secret)
+	step := totpStep(time.Now())
+
+	if w := doRequest(r, http.MethodPost, "/api/profile/2fa/confirm", token, gin.H{"code": "000000"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("confirm with wrong code: %d", w.Code)
+	}
+	w = doRequest(r, http.MethodPost, "/api/profile/2fa/confirm", token, gin.H{"code": totpCode(key, step)})
+	var confirm struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	decodeData(w, &confirm)
+	if w.Code != http.StatusOK || len(confirm.RecoveryCodes) != RecoveryCodeCount {
+		t.Fatalf("confirm: %d %s", w.Code, w.Body)
+	}
+
+	challenge := func() string {
+		w := doRequest(r, http.MethodPost, "/auth/login", "", gin.H{"email": "carol@example.com", "password": "secret1"})
+		var res struct {
+			MFARequired    bool   `json:"mfa_required"`
+			ChallengeToken string `json:"challenge_token"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		if w.Code != http.StatusOK || !res.MFARequired || res.ChallengeToken == "" {
+			t.Fatalf("login should require 2fa: %d %s", w.Code, w.Body)
+		}
+		return res.ChallengeToken
+	}
+	secondStep := func(challenge, code string) *httptest.ResponseRecorder {
+		return doRequest(r, http.MethodPost, "/auth/login/2fa", "", gin.H{"challenge_token": challenge, "code": code})
+	}
+
+	ch := challenge()
+	// 挑战Token不能当作访问Token
+	if w := doRequest(r, http.MethodGet, "/api/profile", ch, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("challenge accepted as access token: %d", w.Code)
+	}
+	// 确认时用过的验证码不能再次使用
+	if res := secondStep(ch, totpCode(key, step)); res.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed code: %d", res.Code)
+	}
+	res := secondStep(ch, totpCode(key, step+1))
+	if res.Code != http.StatusOK {
+		t.Fatalf("second step: %d %s", res.Code, res.Body)
+	}
+	var session LoginResponse
+	json.Unmarshal(res.Body.Bytes(), &session)
+	claims, _ := ParseToken(session.Token)
+	if !claims.MFA {
+		t.Fatal("session should be marked as mfa")
+	}
+	if res := secondStep(ch, totpCode(key, step+1)); res.Code != http.StatusUnauthorized {
+		t.Fatalf("challenge reused: %d", res.Code)
+	}
+
+	// 刷新后仍然保留mfa标记
+	w = doRequest(r, http.MethodPost, "/auth/refresh", "", gin.H{"refresh_token": session.RefreshToken})
+	var refreshed TokenPair
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	if claims, err := ParseToken(refreshed.AccessToken); err != nil || !claims.MFA {
+		t.Fatalf("refreshed session lost mfa: %v", err)
+	}
+
+	// 恢复码只能使用一次
+	recovery := confirm.RecoveryCodes[0]
+	if res := secondStep(challenge(), recovery); res.Code != http.StatusOK {
+		t.Fatalf("recovery code login: %d %s", res.Code, res.Body)
+	}
+	if res := secondStep(challenge(), recovery); res.Code != http.StatusUnauthorized {
+		t.Fatalf("recovery code reused: %d", res.Code)
+	}
+
+	// 关闭两步验证需要完成了两步验证的会话
+	body := gin.H{"code": confirm.RecoveryCodes[1]}
+	if w := doRequest(r, http.MethodDelete, "/api/profile/2fa", token, body); w.Code != http.StatusForbidden {
+		t.Fatalf("disable without mfa session: %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodDelete, "/api/profile/2fa", refreshed.AccessToken, body); w.Code != http.StatusOK {
+		t.Fatalf("disable: %d %s", w.Code, w.Body)
+	}
+	w = doRequest(r, http.MethodPost, "/auth/login", "", gin.H{"email": "carol@example.com", "password": "secret1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login after disabling 2fa: %d", w.Code)
+	}
+	var plain LoginResponse
+	json.Unmarshal(w.Body.Bytes(), &plain)
+	if plain.Token == "" {
+		t.Fatal("login should return a session after disabling 2fa")
+	}
+}
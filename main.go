@@ -3,19 +3,80 @@ package main
 import (
 	"fmt"
 	blog "job/blog"
+	"log/slog"
+	"net/http"
+	"os"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	err := blog.InitDB()
+	cfg, err := blog.LoadConfig(os.Args[1:])
+	if err != nil {
+		panic(fmt.Sprintf("加载配置失败: %v", err))
+	}
+
+	logger, err := blog.NewLogger(cfg.Log, os.Stdout)
+	if err != nil {
+		panic(fmt.Sprintf("初始化日志失败: %v", err))
+	}
+	blog.SetLogger(logger)
+	slog.SetDefault(blog.Logger())
+
+	err = blog.InitDB(cfg.Database)
 	if err != nil {
 		panic(fmt.Sprintf("初始化数据库失败: %v", err))
 	}
+	if cfg.RecountCounters {
+		// 维护命令：全表更新计数后退出，不启动服务
+		err = blog.RecountPostCounters()
+		if err != nil {
+			panic(fmt.Sprintf("重新计算文章计数失败: %v", err))
+		}
+		logger.Info("Post counters recounted")
+		return
+	}
+	err = blog.InitSearch(blog.NewInvertedIndex())
+	if err != nil {
+		panic(fmt.Sprintf("初始化搜索索引失败: %v", err))
+	}
+
+	err = blog.InitJWT(cfg.JWT)
+	if err != nil {
+		panic(fmt.Sprintf("初始化JWT密钥失败: %v", err))
+	}
+	blog.InitRBAC(cfg.Auth)
+	err = blog.InitStorage(cfg.Storage)
+	if err != nil {
+		panic(fmt.Sprintf("初始化附件存储失败: %v", err))
+	}
+	err = blog.InitMailer(cfg.Mail)
+	if err != nil {
+		panic(fmt.Sprintf("初始化邮件发送失败: %v", err))
+	}
+	blog.InitOIDC(cfg.OIDC)
+	err = blog.InitRateLimit(cfg.RateLimit)
+	if err != nil {
+		panic(fmt.Sprintf("初始化限流失败: %v", err))
+	}
+	if cfg.Metrics.Addr != "" {
+		// 指标使用单独的监听地址，不对外暴露
+		go func() {
+			err := http.ListenAndServe(cfg.Metrics.Addr, blog.MetricsHandler(cfg.Metrics.Token))
+			panic(fmt.Sprintf("启动指标服务失败: %v", err))
+		}()
+	}
+	blog.StartScheduler(cfg.Server.SchedulerInterval)
+	defer blog.StopScheduler()
 
-	engine := gin.Default()
+	gin.SetMode(cfg.Server.Mode)
+	// 访问日志和panic恢复由blog.RegisterRoutes中的中间件处理
+	engine := gin.New()
+	blog.LegacyRoutes = cfg.Server.LegacyRoutes
+	blog.TrustedProxies = cfg.Server.TrustedProxies
+	blog.DefaultLocale = cfg.Server.Locale
 	blog.RegisterRoutes(engine)
-	err = engine.Run(":8080")
+	err = engine.Run(cfg.Server.Addr)
 	if err != nil {
 		panic(fmt.Sprintf("启动服务器失败: %v", err))
 	}